@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/radifan9/social-media-backend/internal/configs"
+	"github.com/radifan9/social-media-backend/internal/repositories"
+	"github.com/radifan9/social-media-backend/internal/workers"
+)
+
+// Reconciles public/ against post_images and user_profiles.avatar
+// and removes files nothing points to anymore
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only list the files that would be removed")
+	grace := flag.Duration("grace", time.Hour, "keep unreferenced files younger than this")
+	flag.Parse()
+
+	// Load environment variables
+	if err := godotenv.Load(".env"); err != nil {
+		log.Println("failed to load environment variables\nCause: ", err.Error())
+		return
+	}
+
+	// PostgreSQL DB Initialization
+	db, err := configs.InitDB()
+	if err != nil {
+		log.Println("failed to connect to database\nCause: ", err.Error())
+		return
+	}
+	defer db.Close()
+
+	result, err := workers.CleanupMedia(context.Background(), repositories.NewMediaRepository(db), *grace, *dryRun)
+	if err != nil {
+		log.Println("failed to clean up media\nCause: ", err.Error())
+		return
+	}
+
+	log.Printf("Scanned %d files, removed %d (%d bytes)", result.Scanned, result.Removed, result.Bytes)
+}
@@ -6,7 +6,9 @@ import (
 
 	"github.com/joho/godotenv"
 	"github.com/radifan9/social-media-backend/internal/configs"
+	"github.com/radifan9/social-media-backend/internal/repositories"
 	"github.com/radifan9/social-media-backend/internal/routers"
+	"github.com/radifan9/social-media-backend/internal/workers"
 )
 
 func main() {
@@ -44,8 +46,38 @@ func main() {
 	}
 	log.Println("✅ Successfully connect & ping to rdb!")
 
+	// Content filter rules for posts and comments
+	contentFilter, err := configs.InitContentFilter()
+	if err != nil {
+		log.Println("failed to load content filter\nCause: ", err.Error())
+		return
+	}
+
+	// Scoring weights of the "For You" feed
+	rankingWeights, err := configs.InitRankingWeights()
+	if err != nil {
+		log.Println("failed to load for you config\nCause: ", err.Error())
+		return
+	}
+
+	// Background Workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	mediaWorker := workers.NewMediaWorker(repositories.NewMediaRepository(db), repositories.NewPostRepository(db, rdb))
+	go mediaWorker.Start(workerCtx)
+
+	purgeWorker := workers.NewPurgeWorker(repositories.NewUserRepository(db, rdb))
+	go purgeWorker.Start(workerCtx)
+
+	suggestionWorker := workers.NewSuggestionWorker(repositories.NewUserRepository(db, rdb))
+	go suggestionWorker.Start(workerCtx)
+
+	schedulerWorker := workers.NewSchedulerWorker(repositories.NewPostRepository(db, rdb), contentFilter)
+	go schedulerWorker.Start(workerCtx)
+
 	// Engine Gin Initialization
-	router := routers.InitRouter(db, rdb)
+	router := routers.InitRouter(db, rdb, contentFilter, rankingWeights)
 	router.Run(":8080")
 
 	// Flow of the program
@@ -0,0 +1,19 @@
+-- Drop media columns
+DROP INDEX IF EXISTS public.post_images_pending_idx;
+
+ALTER TABLE public.post_images
+	DROP COLUMN media_type,
+	DROP COLUMN status,
+	DROP COLUMN poster_url,
+	DROP COLUMN size_bytes,
+	DROP COLUMN duration_ms,
+	DROP COLUMN width,
+	DROP COLUMN height,
+	DROP COLUMN error,
+	DROP COLUMN claimed_at,
+	DROP COLUMN processed_at;
+
+ALTER TABLE public.post_images RENAME COLUMN media_url TO image_url;
+
+DROP TYPE IF EXISTS public.media_status;
+DROP TYPE IF EXISTS public.media_type;
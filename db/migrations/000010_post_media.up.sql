@@ -0,0 +1,21 @@
+-- public.post_images media columns
+
+
+CREATE TYPE public.media_type AS ENUM ('image', 'gif', 'video');
+CREATE TYPE public.media_status AS ENUM ('pending', 'processing', 'ready', 'failed');
+
+ALTER TABLE public.post_images RENAME COLUMN image_url TO media_url;
+
+ALTER TABLE public.post_images
+	ADD COLUMN media_type public.media_type DEFAULT 'image' NOT NULL,
+	ADD COLUMN status public.media_status DEFAULT 'ready' NOT NULL,
+	ADD COLUMN poster_url text,
+	ADD COLUMN size_bytes bigint,
+	ADD COLUMN duration_ms integer,
+	ADD COLUMN width integer,
+	ADD COLUMN height integer,
+	ADD COLUMN error text,
+	ADD COLUMN claimed_at timestamptz,
+	ADD COLUMN processed_at timestamptz;
+
+CREATE INDEX post_images_pending_idx ON public.post_images (created_at) WHERE status IN ('pending', 'processing');
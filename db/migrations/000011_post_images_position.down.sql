@@ -0,0 +1,6 @@
+-- Drop ordering and alt text
+ALTER TABLE public.post_images DROP CONSTRAINT IF EXISTS post_images_post_id_position_key;
+
+ALTER TABLE public.post_images
+	DROP COLUMN "position",
+	DROP COLUMN alt_text;
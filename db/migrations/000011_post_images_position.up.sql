@@ -0,0 +1,17 @@
+-- public.post_images ordering and alt text
+
+
+ALTER TABLE public.post_images
+	ADD COLUMN "position" smallint DEFAULT 0 NOT NULL,
+	ADD COLUMN alt_text varchar(1000);
+
+-- Existing rows keep their upload order
+UPDATE public.post_images pi
+SET "position" = ordered.rn - 1
+FROM (
+	SELECT id, ROW_NUMBER() OVER (PARTITION BY post_id ORDER BY created_at, id) AS rn
+	FROM public.post_images
+) ordered
+WHERE pi.id = ordered.id;
+
+ALTER TABLE public.post_images ADD CONSTRAINT post_images_post_id_position_key UNIQUE (post_id, "position");
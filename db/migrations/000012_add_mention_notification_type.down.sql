@@ -0,0 +1,7 @@
+-- Enum values cannot be dropped, so the type is rebuilt without 'mention'
+DELETE FROM public.notifications WHERE "type" = 'mention';
+
+ALTER TYPE public.notification_type RENAME TO notification_type_old;
+CREATE TYPE public.notification_type AS ENUM ('follow', 'like', 'comment');
+ALTER TABLE public.notifications ALTER COLUMN "type" TYPE public.notification_type USING "type"::text::public.notification_type;
+DROP TYPE public.notification_type_old;
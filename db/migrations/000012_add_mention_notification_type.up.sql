@@ -0,0 +1 @@
+ALTER TYPE public.notification_type ADD VALUE IF NOT EXISTS 'mention';
@@ -0,0 +1,4 @@
+-- Drop table
+DROP TABLE public.mentions;
+DROP TABLE public.post_hashtags;
+DROP TABLE public.hashtags;
@@ -0,0 +1,58 @@
+-- public.hashtags definition
+
+
+CREATE TABLE public.hashtags (
+	id uuid DEFAULT gen_random_uuid() NOT NULL,
+	tag varchar(100) NOT NULL,
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT hashtags_pkey PRIMARY KEY (id),
+	CONSTRAINT hashtags_tag_key UNIQUE (tag)
+);
+
+
+-- public.post_hashtags definition
+-- comment_id is set when the hashtag was written in a comment instead of the post text
+
+
+CREATE TABLE public.post_hashtags (
+	id uuid DEFAULT gen_random_uuid() NOT NULL,
+	post_id uuid NOT NULL,
+	comment_id uuid,
+	hashtag_id uuid NOT NULL,
+	start_index integer NOT NULL,
+	end_index integer NOT NULL,
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT post_hashtags_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX post_hashtags_hashtag_id_idx ON public.post_hashtags (hashtag_id, created_at DESC);
+CREATE INDEX post_hashtags_post_id_idx ON public.post_hashtags (post_id);
+
+
+-- public.mentions definition
+
+
+CREATE TABLE public.mentions (
+	id uuid DEFAULT gen_random_uuid() NOT NULL,
+	post_id uuid NOT NULL,
+	comment_id uuid,
+	user_id uuid NOT NULL,
+	handle varchar(50) NOT NULL,
+	start_index integer NOT NULL,
+	end_index integer NOT NULL,
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT mentions_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX mentions_post_id_idx ON public.mentions (post_id);
+CREATE INDEX mentions_user_id_idx ON public.mentions (user_id);
+
+
+-- foreign keys
+
+ALTER TABLE public.post_hashtags ADD CONSTRAINT post_hashtags_post_id_fkey FOREIGN KEY (post_id) REFERENCES public.posts(id) ON DELETE CASCADE;
+ALTER TABLE public.post_hashtags ADD CONSTRAINT post_hashtags_comment_id_fkey FOREIGN KEY (comment_id) REFERENCES public.post_comments(id) ON DELETE CASCADE;
+ALTER TABLE public.post_hashtags ADD CONSTRAINT post_hashtags_hashtag_id_fkey FOREIGN KEY (hashtag_id) REFERENCES public.hashtags(id) ON DELETE CASCADE;
+ALTER TABLE public.mentions ADD CONSTRAINT mentions_post_id_fkey FOREIGN KEY (post_id) REFERENCES public.posts(id) ON DELETE CASCADE;
+ALTER TABLE public.mentions ADD CONSTRAINT mentions_comment_id_fkey FOREIGN KEY (comment_id) REFERENCES public.post_comments(id) ON DELETE CASCADE;
+ALTER TABLE public.mentions ADD CONSTRAINT mentions_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
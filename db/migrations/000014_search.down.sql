@@ -0,0 +1,7 @@
+-- Drop search columns and indexes
+DROP INDEX IF EXISTS public.user_profiles_name_trgm_idx;
+DROP INDEX IF EXISTS public.user_profiles_search_vector_idx;
+DROP INDEX IF EXISTS public.posts_search_vector_idx;
+
+ALTER TABLE public.user_profiles DROP COLUMN search_vector;
+ALTER TABLE public.posts DROP COLUMN search_vector;
@@ -0,0 +1,18 @@
+-- Full-text and fuzzy search
+
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE public.posts
+	ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(text_content, ''))) STORED;
+
+CREATE INDEX posts_search_vector_idx ON public.posts USING GIN (search_vector);
+
+ALTER TABLE public.user_profiles
+	ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple', COALESCE("name", '')), 'A') ||
+		setweight(to_tsvector('simple', COALESCE(bio, '')), 'B')
+	) STORED;
+
+CREATE INDEX user_profiles_search_vector_idx ON public.user_profiles USING GIN (search_vector);
+CREATE INDEX user_profiles_name_trgm_idx ON public.user_profiles USING GIN ("name" gin_trgm_ops);
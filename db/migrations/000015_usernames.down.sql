@@ -0,0 +1,7 @@
+-- Drop handles
+DROP INDEX IF EXISTS public.user_profiles_username_trgm_idx;
+DROP INDEX IF EXISTS public.user_profiles_username_key;
+
+ALTER TABLE public.user_profiles
+	DROP COLUMN username,
+	DROP COLUMN username_changed_at;
@@ -0,0 +1,10 @@
+-- public.user_profiles handles
+
+
+ALTER TABLE public.user_profiles
+	ADD COLUMN username varchar(30),
+	ADD COLUMN username_changed_at timestamptz;
+
+-- Handles are unique regardless of case
+CREATE UNIQUE INDEX user_profiles_username_key ON public.user_profiles (LOWER(username));
+CREATE INDEX user_profiles_username_trgm_idx ON public.user_profiles USING GIN (username gin_trgm_ops);
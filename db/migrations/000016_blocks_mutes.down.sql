@@ -0,0 +1,3 @@
+-- Drop table
+DROP TABLE public.user_mutes;
+DROP TABLE public.user_blocks;
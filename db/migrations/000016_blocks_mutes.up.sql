@@ -0,0 +1,32 @@
+-- public.user_blocks definition
+
+
+CREATE TABLE public.user_blocks (
+	blocker_id uuid NOT NULL,
+	blocked_id uuid NOT NULL,
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT check_not_self_block CHECK ((blocker_id <> blocked_id)),
+	CONSTRAINT user_blocks_pkey PRIMARY KEY (blocker_id, blocked_id)
+);
+
+CREATE INDEX user_blocks_blocked_id_idx ON public.user_blocks (blocked_id);
+
+
+-- public.user_mutes definition
+
+
+CREATE TABLE public.user_mutes (
+	muter_id uuid NOT NULL,
+	muted_id uuid NOT NULL,
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT check_not_self_mute CHECK ((muter_id <> muted_id)),
+	CONSTRAINT user_mutes_pkey PRIMARY KEY (muter_id, muted_id)
+);
+
+
+-- foreign keys
+
+ALTER TABLE public.user_blocks ADD CONSTRAINT user_blocks_blocker_id_fkey FOREIGN KEY (blocker_id) REFERENCES public.users(id) ON DELETE CASCADE;
+ALTER TABLE public.user_blocks ADD CONSTRAINT user_blocks_blocked_id_fkey FOREIGN KEY (blocked_id) REFERENCES public.users(id) ON DELETE CASCADE;
+ALTER TABLE public.user_mutes ADD CONSTRAINT user_mutes_muter_id_fkey FOREIGN KEY (muter_id) REFERENCES public.users(id) ON DELETE CASCADE;
+ALTER TABLE public.user_mutes ADD CONSTRAINT user_mutes_muted_id_fkey FOREIGN KEY (muted_id) REFERENCES public.users(id) ON DELETE CASCADE;
@@ -0,0 +1,4 @@
+-- Drop table
+DROP TABLE public.follow_requests;
+
+ALTER TABLE public.user_profiles DROP COLUMN is_private;
@@ -0,0 +1,24 @@
+-- public.user_profiles privacy flag
+
+
+ALTER TABLE public.user_profiles ADD COLUMN is_private boolean DEFAULT false NOT NULL;
+
+
+-- public.follow_requests definition
+
+
+CREATE TABLE public.follow_requests (
+	requester_id uuid NOT NULL,
+	target_id uuid NOT NULL,
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT check_not_self_request CHECK ((requester_id <> target_id)),
+	CONSTRAINT follow_requests_pkey PRIMARY KEY (requester_id, target_id)
+);
+
+CREATE INDEX follow_requests_target_id_idx ON public.follow_requests (target_id, created_at DESC);
+
+
+-- public.follow_requests foreign keys
+
+ALTER TABLE public.follow_requests ADD CONSTRAINT follow_requests_requester_id_fkey FOREIGN KEY (requester_id) REFERENCES public.users(id) ON DELETE CASCADE;
+ALTER TABLE public.follow_requests ADD CONSTRAINT follow_requests_target_id_fkey FOREIGN KEY (target_id) REFERENCES public.users(id) ON DELETE CASCADE;
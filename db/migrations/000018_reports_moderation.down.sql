@@ -0,0 +1,17 @@
+-- Drop tables
+DROP TABLE public.moderation_audit_log;
+DROP FUNCTION public.moderation_audit_log_append_only();
+DROP TABLE public.reports;
+
+-- Drop types
+DROP TYPE public.report_resolution;
+DROP TYPE public.report_status;
+DROP TYPE public.report_reason;
+DROP TYPE public.report_target;
+
+ALTER TABLE public.post_comments DROP COLUMN hidden_at;
+ALTER TABLE public.posts DROP COLUMN hidden_at;
+
+ALTER TABLE public.users DROP COLUMN suspended_until;
+ALTER TABLE public.users DROP CONSTRAINT check_user_role;
+ALTER TABLE public.users DROP COLUMN "role";
@@ -0,0 +1,91 @@
+-- public.users role and suspension
+
+
+ALTER TABLE public.users ADD COLUMN "role" varchar(20) DEFAULT 'user' NOT NULL;
+ALTER TABLE public.users ADD CONSTRAINT check_user_role CHECK (("role" IN ('user', 'moderator', 'admin')));
+ALTER TABLE public.users ADD COLUMN suspended_until timestamptz;
+
+
+-- hidden content
+
+
+ALTER TABLE public.posts ADD COLUMN hidden_at timestamptz;
+ALTER TABLE public.post_comments ADD COLUMN hidden_at timestamptz;
+
+
+-- report types
+
+
+CREATE TYPE public.report_target AS ENUM ('post', 'comment', 'user');
+CREATE TYPE public.report_reason AS ENUM ('spam', 'harassment', 'hate_speech', 'violence', 'sexual_content', 'self_harm', 'misinformation', 'impersonation', 'other');
+CREATE TYPE public.report_status AS ENUM ('open', 'claimed', 'resolved');
+CREATE TYPE public.report_resolution AS ENUM ('dismiss', 'hide', 'delete', 'suspend');
+
+
+-- public.reports definition
+
+
+CREATE TABLE public.reports (
+	id uuid DEFAULT gen_random_uuid() NOT NULL,
+	reporter_id uuid NOT NULL,
+	target_type public.report_target NOT NULL,
+	target_user_id uuid NOT NULL,
+	post_id uuid,
+	comment_id uuid,
+	reason public.report_reason NOT NULL,
+	details varchar(1000),
+	content_snapshot text,
+	status public.report_status DEFAULT 'open' NOT NULL,
+	assigned_to uuid,
+	resolution public.report_resolution,
+	resolution_note varchar(1000),
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+	claimed_at timestamptz,
+	resolved_at timestamptz,
+	CONSTRAINT reports_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX reports_queue_idx ON public.reports (status, created_at, id);
+-- One open report per reporter and target
+CREATE UNIQUE INDEX reports_open_target_key ON public.reports (reporter_id, target_type, target_user_id, COALESCE(post_id, target_user_id), COALESCE(comment_id, target_user_id)) WHERE status <> 'resolved';
+
+
+-- public.reports foreign keys
+
+ALTER TABLE public.reports ADD CONSTRAINT reports_reporter_id_fkey FOREIGN KEY (reporter_id) REFERENCES public.users(id) ON DELETE CASCADE;
+ALTER TABLE public.reports ADD CONSTRAINT reports_target_user_id_fkey FOREIGN KEY (target_user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+ALTER TABLE public.reports ADD CONSTRAINT reports_post_id_fkey FOREIGN KEY (post_id) REFERENCES public.posts(id) ON DELETE SET NULL;
+ALTER TABLE public.reports ADD CONSTRAINT reports_comment_id_fkey FOREIGN KEY (comment_id) REFERENCES public.post_comments(id) ON DELETE SET NULL;
+ALTER TABLE public.reports ADD CONSTRAINT reports_assigned_to_fkey FOREIGN KEY (assigned_to) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
+-- public.moderation_audit_log definition
+-- Ids are kept without foreign keys so entries outlive the rows they describe
+
+
+CREATE TABLE public.moderation_audit_log (
+	id bigserial NOT NULL,
+	moderator_id uuid NOT NULL,
+	"action" varchar(30) NOT NULL,
+	report_id uuid,
+	target_type public.report_target,
+	target_id uuid,
+	details jsonb DEFAULT '{}' NOT NULL,
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	CONSTRAINT moderation_audit_log_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX moderation_audit_log_report_id_idx ON public.moderation_audit_log (report_id);
+
+
+-- append-only guard
+
+CREATE FUNCTION public.moderation_audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'moderation_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER moderation_audit_log_no_change
+	BEFORE UPDATE OR DELETE OR TRUNCATE ON public.moderation_audit_log
+	FOR EACH STATEMENT EXECUTE FUNCTION public.moderation_audit_log_append_only();
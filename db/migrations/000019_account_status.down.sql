@@ -0,0 +1,4 @@
+ALTER TABLE public.users DROP CONSTRAINT check_suspended_until;
+ALTER TABLE public.users DROP COLUMN status;
+
+DROP TYPE public.account_status;
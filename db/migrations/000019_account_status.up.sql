@@ -0,0 +1,12 @@
+-- public.users account status
+
+
+CREATE TYPE public.account_status AS ENUM ('active', 'suspended', 'banned');
+
+ALTER TABLE public.users ADD COLUMN status public.account_status DEFAULT 'active' NOT NULL;
+
+-- Suspensions made from the report queue before this migration
+UPDATE public.users SET status = 'suspended' WHERE suspended_until > CURRENT_TIMESTAMP;
+UPDATE public.users SET suspended_until = NULL WHERE status = 'active';
+
+ALTER TABLE public.users ADD CONSTRAINT check_suspended_until CHECK (((status = 'suspended') = (suspended_until IS NOT NULL)));
@@ -0,0 +1,15 @@
+-- Enum values cannot be dropped, so the types are rebuilt without 'content_filter' and 'restore'
+DELETE FROM public.reports WHERE reason = 'content_filter' OR reporter_id IS NULL;
+UPDATE public.reports SET resolution = 'dismiss' WHERE resolution = 'restore';
+
+ALTER TYPE public.report_reason RENAME TO report_reason_old;
+CREATE TYPE public.report_reason AS ENUM ('spam', 'harassment', 'hate_speech', 'violence', 'sexual_content', 'self_harm', 'misinformation', 'impersonation', 'other');
+ALTER TABLE public.reports ALTER COLUMN reason TYPE public.report_reason USING reason::text::public.report_reason;
+DROP TYPE public.report_reason_old;
+
+ALTER TYPE public.report_resolution RENAME TO report_resolution_old;
+CREATE TYPE public.report_resolution AS ENUM ('dismiss', 'hide', 'delete', 'suspend');
+ALTER TABLE public.reports ALTER COLUMN resolution TYPE public.report_resolution USING resolution::text::public.report_resolution;
+DROP TYPE public.report_resolution_old;
+
+ALTER TABLE public.reports ALTER COLUMN reporter_id SET NOT NULL;
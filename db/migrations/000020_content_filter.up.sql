@@ -0,0 +1,9 @@
+-- Reports raised by the content filter have no reporter
+
+
+ALTER TABLE public.reports ALTER COLUMN reporter_id DROP NOT NULL;
+
+ALTER TYPE public.report_reason ADD VALUE IF NOT EXISTS 'content_filter';
+
+-- Moderators can put hidden or held content back
+ALTER TYPE public.report_resolution ADD VALUE IF NOT EXISTS 'restore';
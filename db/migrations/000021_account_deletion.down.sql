@@ -0,0 +1,3 @@
+DROP INDEX public.users_deleted_at_idx;
+
+ALTER TABLE public.users DROP COLUMN deleted_at;
@@ -0,0 +1,6 @@
+-- public.users soft delete
+
+
+ALTER TABLE public.users ADD COLUMN deleted_at timestamptz;
+
+CREATE INDEX users_deleted_at_idx ON public.users (deleted_at) WHERE deleted_at IS NOT NULL;
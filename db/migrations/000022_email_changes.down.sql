@@ -0,0 +1,2 @@
+-- Drop table
+DROP TABLE public.email_changes;
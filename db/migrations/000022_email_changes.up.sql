@@ -0,0 +1,26 @@
+-- public.email_changes definition
+-- Tokens are stored as sha256 hashes, the raw values only travel in the emails
+
+
+CREATE TABLE public.email_changes (
+	id uuid DEFAULT gen_random_uuid() NOT NULL,
+	user_id uuid NOT NULL,
+	old_email varchar(255) NOT NULL,
+	new_email varchar(255) NOT NULL,
+	confirm_token_hash char(64) NOT NULL,
+	revert_token_hash char(64) NOT NULL,
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	confirmed_at timestamptz,
+	reverted_at timestamptz,
+	cancelled_at timestamptz,
+	CONSTRAINT email_changes_pkey PRIMARY KEY (id),
+	CONSTRAINT email_changes_confirm_token_hash_key UNIQUE (confirm_token_hash),
+	CONSTRAINT email_changes_revert_token_hash_key UNIQUE (revert_token_hash)
+);
+
+CREATE INDEX email_changes_user_id_idx ON public.email_changes (user_id);
+
+
+-- public.email_changes foreign keys
+
+ALTER TABLE public.email_changes ADD CONSTRAINT email_changes_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
@@ -0,0 +1,11 @@
+DELETE FROM public.posts WHERE reposted_post_id IS NOT NULL;
+
+DROP INDEX public.posts_quoted_post_id_idx;
+DROP INDEX public.posts_user_reposted_post_key;
+
+ALTER TABLE public.posts
+	DROP CONSTRAINT posts_quoted_post_id_fkey,
+	DROP CONSTRAINT posts_reposted_post_id_fkey,
+	DROP CONSTRAINT posts_repost_or_quote_check,
+	DROP COLUMN quoted_post_id,
+	DROP COLUMN reposted_post_id;
@@ -0,0 +1,16 @@
+-- public.posts reposts and quote posts
+-- A repost is a post without content pointing at the original, a quote is a normal post
+-- embedding another one. Reposts go away with the original, quotes keep their own text
+
+
+ALTER TABLE public.posts
+	ADD COLUMN reposted_post_id uuid,
+	ADD COLUMN quoted_post_id uuid,
+	ADD CONSTRAINT posts_repost_or_quote_check CHECK (reposted_post_id IS NULL OR quoted_post_id IS NULL);
+
+ALTER TABLE public.posts ADD CONSTRAINT posts_reposted_post_id_fkey FOREIGN KEY (reposted_post_id) REFERENCES public.posts(id) ON DELETE CASCADE;
+ALTER TABLE public.posts ADD CONSTRAINT posts_quoted_post_id_fkey FOREIGN KEY (quoted_post_id) REFERENCES public.posts(id) ON DELETE SET NULL;
+
+-- One repost per user and post, also used to count reposts
+CREATE UNIQUE INDEX posts_user_reposted_post_key ON public.posts (reposted_post_id, user_id) WHERE reposted_post_id IS NOT NULL;
+CREATE INDEX posts_quoted_post_id_idx ON public.posts (quoted_post_id) WHERE quoted_post_id IS NOT NULL;
@@ -0,0 +1,18 @@
+-- public.bookmarks definition
+-- Only ever read by the user who saved the post
+
+
+CREATE TABLE public.bookmarks (
+	user_id uuid NOT NULL,
+	post_id uuid NOT NULL,
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	CONSTRAINT bookmarks_pkey PRIMARY KEY (user_id, post_id)
+);
+
+CREATE INDEX bookmarks_user_id_created_at_idx ON public.bookmarks (user_id, created_at DESC, post_id DESC);
+
+
+-- public.bookmarks foreign keys
+
+ALTER TABLE public.bookmarks ADD CONSTRAINT bookmarks_post_id_fkey FOREIGN KEY (post_id) REFERENCES public.posts(id) ON DELETE CASCADE;
+ALTER TABLE public.bookmarks ADD CONSTRAINT bookmarks_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS public.follow_suggestions;
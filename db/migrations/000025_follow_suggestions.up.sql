@@ -0,0 +1,20 @@
+-- public.follow_suggestions definition
+-- Precomputed by the suggestion worker, exclusions are applied again when read
+
+
+CREATE TABLE public.follow_suggestions (
+	user_id uuid NOT NULL,
+	suggested_id uuid NOT NULL,
+	mutual_count int4 NOT NULL,
+	score float8 NOT NULL,
+	computed_at timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	CONSTRAINT follow_suggestions_pkey PRIMARY KEY (user_id, suggested_id)
+);
+
+CREATE INDEX follow_suggestions_user_id_score_idx ON public.follow_suggestions (user_id, score DESC);
+
+
+-- public.follow_suggestions foreign keys
+
+ALTER TABLE public.follow_suggestions ADD CONSTRAINT follow_suggestions_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+ALTER TABLE public.follow_suggestions ADD CONSTRAINT follow_suggestions_suggested_id_fkey FOREIGN KEY (suggested_id) REFERENCES public.users(id) ON DELETE CASCADE;
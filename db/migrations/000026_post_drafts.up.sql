@@ -0,0 +1,23 @@
+-- public.post_drafts definition
+-- Drafts have no publish_at, scheduled posts do. A draft is deleted once its post is published
+
+
+CREATE TABLE public.post_drafts (
+	id uuid DEFAULT gen_random_uuid() NOT NULL,
+	user_id uuid NOT NULL,
+	text_content text DEFAULT '' NOT NULL,
+	media jsonb DEFAULT '[]' NOT NULL,
+	publish_at timestamptz,
+	failure_reason text,
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	updated_at timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	CONSTRAINT post_drafts_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX post_drafts_user_id_idx ON public.post_drafts (user_id, created_at DESC, id DESC);
+CREATE INDEX post_drafts_publish_at_idx ON public.post_drafts (publish_at) WHERE publish_at IS NOT NULL;
+
+
+-- public.post_drafts foreign keys
+
+ALTER TABLE public.post_drafts ADD CONSTRAINT post_drafts_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.poll_votes;
+DROP TABLE IF EXISTS public.poll_options;
+DROP TABLE IF EXISTS public.polls;
@@ -0,0 +1,46 @@
+-- public.polls definition
+-- A poll belongs to exactly one post and closes at a fixed time
+
+
+CREATE TABLE public.polls (
+	post_id uuid NOT NULL,
+	closes_at timestamptz NOT NULL,
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	CONSTRAINT polls_pkey PRIMARY KEY (post_id)
+);
+
+
+-- public.poll_options definition
+
+CREATE TABLE public.poll_options (
+	id uuid DEFAULT gen_random_uuid() NOT NULL,
+	post_id uuid NOT NULL,
+	"position" int4 NOT NULL,
+	"label" text NOT NULL,
+	CONSTRAINT poll_options_pkey PRIMARY KEY (id),
+	CONSTRAINT poll_options_post_id_position_key UNIQUE (post_id, "position"),
+	CONSTRAINT poll_options_position_check CHECK ("position" BETWEEN 0 AND 3)
+);
+
+
+-- public.poll_votes definition
+-- The primary key allows one vote per user and poll, even with concurrent voters
+
+CREATE TABLE public.poll_votes (
+	post_id uuid NOT NULL,
+	user_id uuid NOT NULL,
+	option_id uuid NOT NULL,
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	CONSTRAINT poll_votes_pkey PRIMARY KEY (post_id, user_id)
+);
+
+CREATE INDEX poll_votes_option_id_idx ON public.poll_votes (option_id);
+
+
+-- foreign keys
+
+ALTER TABLE public.polls ADD CONSTRAINT polls_post_id_fkey FOREIGN KEY (post_id) REFERENCES public.posts(id) ON DELETE CASCADE;
+ALTER TABLE public.poll_options ADD CONSTRAINT poll_options_post_id_fkey FOREIGN KEY (post_id) REFERENCES public.polls(post_id) ON DELETE CASCADE;
+ALTER TABLE public.poll_votes ADD CONSTRAINT poll_votes_post_id_fkey FOREIGN KEY (post_id) REFERENCES public.polls(post_id) ON DELETE CASCADE;
+ALTER TABLE public.poll_votes ADD CONSTRAINT poll_votes_option_id_fkey FOREIGN KEY (option_id) REFERENCES public.poll_options(id) ON DELETE CASCADE;
+ALTER TABLE public.poll_votes ADD CONSTRAINT poll_votes_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
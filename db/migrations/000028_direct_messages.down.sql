@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.messages;
+DROP TABLE IF EXISTS public.conversation_participants;
+DROP TABLE IF EXISTS public.conversations;
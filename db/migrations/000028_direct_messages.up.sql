@@ -0,0 +1,54 @@
+-- public.conversations definition
+-- direct_key is "<smaller user id>:<larger user id>" for 1:1 conversations,
+-- so two users always share a single one. Groups have no direct_key
+
+
+CREATE TABLE public.conversations (
+	id uuid DEFAULT gen_random_uuid() NOT NULL,
+	is_group bool DEFAULT false NOT NULL,
+	"name" varchar(100),
+	direct_key text,
+	created_by uuid,
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	last_message_at timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	CONSTRAINT conversations_pkey PRIMARY KEY (id),
+	CONSTRAINT conversations_direct_key_key UNIQUE (direct_key),
+	CONSTRAINT conversations_direct_key_check CHECK (is_group = (direct_key IS NULL))
+);
+
+
+-- public.conversation_participants definition
+-- last_read_at is the read receipt of the participant
+
+CREATE TABLE public.conversation_participants (
+	conversation_id uuid NOT NULL,
+	user_id uuid NOT NULL,
+	joined_at timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	last_read_at timestamptz,
+	CONSTRAINT conversation_participants_pkey PRIMARY KEY (conversation_id, user_id)
+);
+
+CREATE INDEX conversation_participants_user_id_idx ON public.conversation_participants (user_id);
+
+
+-- public.messages definition
+
+CREATE TABLE public.messages (
+	id uuid DEFAULT gen_random_uuid() NOT NULL,
+	conversation_id uuid NOT NULL,
+	sender_id uuid NOT NULL,
+	body text NOT NULL,
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	CONSTRAINT messages_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX messages_conversation_id_idx ON public.messages (conversation_id, created_at DESC, id DESC);
+
+
+-- foreign keys
+
+ALTER TABLE public.conversations ADD CONSTRAINT conversations_created_by_fkey FOREIGN KEY (created_by) REFERENCES public.users(id) ON DELETE SET NULL;
+ALTER TABLE public.conversation_participants ADD CONSTRAINT conversation_participants_conversation_id_fkey FOREIGN KEY (conversation_id) REFERENCES public.conversations(id) ON DELETE CASCADE;
+ALTER TABLE public.conversation_participants ADD CONSTRAINT conversation_participants_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+ALTER TABLE public.messages ADD CONSTRAINT messages_conversation_id_fkey FOREIGN KEY (conversation_id) REFERENCES public.conversations(id) ON DELETE CASCADE;
+ALTER TABLE public.messages ADD CONSTRAINT messages_sender_id_fkey FOREIGN KEY (sender_id) REFERENCES public.users(id) ON DELETE CASCADE;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS public.close_friends;
+
+ALTER TABLE public.post_drafts DROP CONSTRAINT IF EXISTS post_drafts_visibility_check;
+ALTER TABLE public.post_drafts DROP COLUMN IF EXISTS visibility;
+
+ALTER TABLE public.posts DROP CONSTRAINT IF EXISTS posts_visibility_check;
+ALTER TABLE public.posts DROP COLUMN IF EXISTS visibility;
@@ -0,0 +1,29 @@
+-- Audience of a post: everyone, followers of the author, the author's close friends
+-- or only the users mentioned in the post. The author always sees their own posts
+
+ALTER TABLE public.posts ADD COLUMN visibility varchar(20) DEFAULT 'public' NOT NULL;
+ALTER TABLE public.posts ADD CONSTRAINT posts_visibility_check CHECK (visibility IN ('public', 'followers', 'close_friends', 'mentioned'));
+
+-- Drafts keep the audience their post is published to
+ALTER TABLE public.post_drafts ADD COLUMN visibility varchar(20) DEFAULT 'public' NOT NULL;
+ALTER TABLE public.post_drafts ADD CONSTRAINT post_drafts_visibility_check CHECK (visibility IN ('public', 'followers', 'close_friends', 'mentioned'));
+
+
+-- public.close_friends definition
+-- Managed by user_id, only ever read when checking the audience of their posts
+
+CREATE TABLE public.close_friends (
+	user_id uuid NOT NULL,
+	friend_id uuid NOT NULL,
+	created_at timestamptz DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	CONSTRAINT close_friends_pkey PRIMARY KEY (user_id, friend_id),
+	CONSTRAINT close_friends_check CHECK (user_id <> friend_id)
+);
+
+CREATE INDEX close_friends_friend_id_idx ON public.close_friends (friend_id);
+
+
+-- public.close_friends foreign keys
+
+ALTER TABLE public.close_friends ADD CONSTRAINT close_friends_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+ALTER TABLE public.close_friends ADD CONSTRAINT close_friends_friend_id_fkey FOREIGN KEY (friend_id) REFERENCES public.users(id) ON DELETE CASCADE;
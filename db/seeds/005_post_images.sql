@@ -1,4 +1,4 @@
-INSERT INTO public.post_images (id,post_id,image_url,created_at) OVERRIDING SYSTEM VALUE VALUES
+INSERT INTO public.post_images (id,post_id,media_url,created_at) OVERRIDING SYSTEM VALUE VALUES
 	 ('5af8d997-cc9a-49ea-8326-3af0b9543182'::uuid,'11ba1f81-5ce2-4e8f-b7ea-19fca0ed1fd0'::uuid,'public/post_images/1759288212655273741_images_c81d8a97-17e6-4ba0-a3d3-b26f75b3fe4a.jpg','2025-10-01 10:10:12.655694+07'),
 	 ('bcd6331e-e38e-4a17-ba16-b06428f60149'::uuid,'a0ece4d0-2e0b-4166-a624-c04a9e795c3f'::uuid,'public/post_images/1759288241113908394_images_c81d8a97-17e6-4ba0-a3d3-b26f75b3fe4a.jpg','2025-10-01 10:10:41.114164+07'),
 	 ('ae27af5c-9335-4722-8126-6352228acd00'::uuid,'439072b4-2055-4148-8f11-05508528c7f4'::uuid,'public/post_images/1759288263399032749_images_c81d8a97-17e6-4ba0-a3d3-b26f75b3fe4a.jpg','2025-10-01 10:11:03.39968+07'),
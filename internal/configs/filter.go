@@ -0,0 +1,11 @@
+package configs
+
+import (
+	"os"
+
+	"github.com/radifan9/social-media-backend/internal/filters"
+)
+
+func InitContentFilter() (filters.ContentFilter, error) {
+	return filters.LoadConfig(os.Getenv("CONTENT_FILTER_CONFIG"))
+}
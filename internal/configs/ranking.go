@@ -0,0 +1,11 @@
+package configs
+
+import (
+	"os"
+
+	"github.com/radifan9/social-media-backend/internal/ranking"
+)
+
+func InitRankingWeights() (ranking.Weights, error) {
+	return ranking.LoadWeights(os.Getenv("FOR_YOU_CONFIG"))
+}
@@ -0,0 +1,63 @@
+package filters
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Rules is one group of terms that lead to the same action
+type Rules struct {
+	Words    []string `json:"words"`
+	Patterns []string `json:"patterns"`
+	Domains  []string `json:"domains"`
+}
+
+// Config is the JSON file pointed to by CONTENT_FILTER_CONFIG
+//
+//	{
+//	  "reject": {"words": ["..."], "patterns": ["(?i)..."], "domains": ["spam.example"]},
+//	  "hold":   {"words": ["..."], "patterns": [], "domains": []}
+//	}
+type Config struct {
+	Reject Rules `json:"reject"`
+	Hold   Rules `json:"hold"`
+}
+
+// LoadConfig reads the filter rules from path. An empty path gives a chain that allows everything
+func LoadConfig(path string) (Chain, error) {
+	if path == "" {
+		return Chain{}, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid content filter config: %w", err)
+	}
+
+	return NewChain(cfg)
+}
+
+// NewChain builds the word and domain filters for both rule groups, rejections run first
+func NewChain(cfg Config) (Chain, error) {
+	var chain Chain
+	for _, group := range []struct {
+		action Action
+		rules  Rules
+	}{
+		{Reject, cfg.Reject},
+		{Hold, cfg.Hold},
+	} {
+		words, err := NewWordFilter(group.action, group.rules.Words, group.rules.Patterns)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, words, NewDomainFilter(group.action, group.rules.Domains))
+	}
+	return chain, nil
+}
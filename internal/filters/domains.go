@@ -0,0 +1,47 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Links with or without a scheme, e.g. https://example.com/x or www.example.com
+var linkRe = regexp.MustCompile(`(?i)\b(?:https?://)?((?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,})\b`)
+
+// DomainFilter matches links to blocked domains and any of their subdomains
+type DomainFilter struct {
+	action  Action
+	domains []string
+}
+
+func NewDomainFilter(action Action, domains []string) *DomainFilter {
+	f := &DomainFilter{action: action}
+	for _, d := range domains {
+		d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "www.")
+		if d != "" {
+			f.domains = append(f.domains, d)
+		}
+	}
+	return f
+}
+
+func (f *DomainFilter) Check(_ context.Context, text string) Verdict {
+	if len(f.domains) == 0 {
+		return Verdict{Action: Allow}
+	}
+
+	for _, m := range linkRe.FindAllStringSubmatch(text, -1) {
+		host := strings.ToLower(m[1])
+		for _, d := range f.domains {
+			if host == d || strings.HasSuffix(host, "."+d) {
+				if f.action == Hold {
+					return Verdict{Action: Hold, Reason: fmt.Sprintf("links to %s need review by a moderator", d)}
+				}
+				return Verdict{Action: f.action, Reason: fmt.Sprintf("links to %s are not allowed", d)}
+			}
+		}
+	}
+	return Verdict{Action: Allow}
+}
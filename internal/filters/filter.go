@@ -0,0 +1,51 @@
+package filters
+
+import "context"
+
+// Action is what should happen to a piece of content
+type Action int
+
+const (
+	Allow Action = iota
+	// Hold stores the content hidden until a moderator looks at it
+	Hold
+	Reject
+)
+
+func (a Action) String() string {
+	switch a {
+	case Hold:
+		return "hold"
+	case Reject:
+		return "reject"
+	}
+	return "allow"
+}
+
+type Verdict struct {
+	Action Action
+	// Shown to the author when content is rejected or held
+	Reason string
+}
+
+// ContentFilter checks post and comment text before it is stored
+type ContentFilter interface {
+	Check(ctx context.Context, text string) Verdict
+}
+
+// Chain runs every filter and returns the strictest verdict, the first reason wins on ties
+type Chain []ContentFilter
+
+func (c Chain) Check(ctx context.Context, text string) Verdict {
+	verdict := Verdict{Action: Allow}
+	for _, f := range c {
+		v := f.Check(ctx, text)
+		if v.Action > verdict.Action {
+			verdict = v
+		}
+		if verdict.Action == Reject {
+			break
+		}
+	}
+	return verdict
+}
@@ -0,0 +1,50 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// WordFilter matches banned words (whole words, case-insensitive) and regular expressions
+type WordFilter struct {
+	action   Action
+	patterns []*regexp.Regexp
+}
+
+func NewWordFilter(action Action, words, patterns []string) (*WordFilter, error) {
+	f := &WordFilter{action: action}
+
+	for _, w := range words {
+		w = strings.TrimSpace(w)
+		if w == "" {
+			continue
+		}
+		// \b only knows ASCII word characters, so the boundaries are spelled out to
+		// keep words in other scripts from matching inside longer words
+		f.patterns = append(f.patterns, regexp.MustCompile(`(?i)(^|[^\p{L}\p{M}\p{N}_])`+regexp.QuoteMeta(w)+`($|[^\p{L}\p{M}\p{N}_])`))
+	}
+
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		f.patterns = append(f.patterns, re)
+	}
+
+	return f, nil
+}
+
+func (f *WordFilter) Check(_ context.Context, text string) Verdict {
+	for _, re := range f.patterns {
+		if re.MatchString(text) {
+			if f.action == Hold {
+				return Verdict{Action: Hold, Reason: "text needs review by a moderator"}
+			}
+			return Verdict{Action: f.action, Reason: "text contains a banned term"}
+		}
+	}
+	return Verdict{Action: Allow}
+}
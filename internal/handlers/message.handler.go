@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/radifan9/social-media-backend/internal/repositories"
+	"github.com/radifan9/social-media-backend/internal/utils"
+	"github.com/radifan9/social-media-backend/pkg"
+)
+
+const (
+	maxMessageLength          = 2000
+	maxConversationNameLength = 100
+	// Participants of a group, including its creator
+	maxConversationSize = 10
+	// Comment line sent on idle streams so proxies keep the connection open
+	streamKeepAlive = 30 * time.Second
+)
+
+type MessageHandler struct {
+	mr *repositories.MessageRepository
+}
+
+func NewMessageHandler(mr *repositories.MessageRepository) *MessageHandler {
+	return &MessageHandler{mr: mr}
+}
+
+// CreateConversation starts a 1:1 conversation with one user, or a group with several
+func (m *MessageHandler) CreateConversation(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	// Bind request body
+	var body models.CreateConversation
+	if err := ctx.ShouldBind(&body); err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	var participantIDs []string
+	for _, id := range body.ParticipantIDs {
+		if !utils.IsUUID(id) {
+			utils.HandleError(ctx, http.StatusNotFound, "user not found", "participant id must be a uuid")
+			return
+		}
+		if !slices.Contains(participantIDs, id) {
+			participantIDs = append(participantIDs, id)
+		}
+	}
+	if len(participantIDs) == 0 {
+		utils.HandleError(ctx, http.StatusBadRequest, "a conversation needs at least one other participant", "no participants")
+		return
+	}
+	if len(participantIDs)+1 > maxConversationSize {
+		utils.HandleError(ctx, http.StatusBadRequest, fmt.Sprintf("a group may have at most %d participants", maxConversationSize), "group too large")
+		return
+	}
+
+	var name *string
+	if body.Name != nil && len(participantIDs) > 1 {
+		if trimmed := strings.TrimSpace(*body.Name); trimmed != "" {
+			if utf8.RuneCountInString(trimmed) > maxConversationNameLength {
+				utils.HandleError(ctx, http.StatusBadRequest, fmt.Sprintf("name may be at most %d characters", maxConversationNameLength), "name too long")
+				return
+			}
+			name = &trimmed
+		}
+	}
+
+	conversation, err := m.mr.CreateConversation(ctx, user.UserId, participantIDs, name)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrUserNotFound):
+			utils.HandleError(ctx, http.StatusNotFound, "user not found", err.Error())
+		case errors.Is(err, repositories.ErrPrivateAccount):
+			utils.HandleError(ctx, http.StatusForbidden, "private accounts can only be messaged by their followers", err.Error())
+		case errors.Is(err, repositories.ErrSelfAction):
+			utils.HandleError(ctx, http.StatusBadRequest, "you cannot message yourself", err.Error())
+		case errors.Is(err, repositories.ErrParticipantsBlocked):
+			utils.HandleError(ctx, http.StatusConflict, err.Error(), err.Error())
+		default:
+			utils.Error(ctx, http.StatusInternalServerError, "failed to create conversation", err)
+		}
+		return
+	}
+
+	utils.Success(ctx, http.StatusCreated, conversation)
+}
+
+// GetConversations lists the conversations of the user with their unread counts
+func (m *MessageHandler) GetConversations(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	cursorTime, cursorID, err := pkg.ParseCursor(ctx.Query("cursor"))
+	if err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid cursor", err.Error())
+		return
+	}
+
+	page, err := m.mr.GetConversations(ctx, user.UserId, cursorTime, cursorID)
+	if err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, page)
+}
+
+func (m *MessageHandler) GetUnreadCount(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	unread, err := m.mr.GetUnreadCount(ctx, user.UserId)
+	if err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, unread)
+}
+
+func (m *MessageHandler) GetConversation(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	conversationID := ctx.Param("id")
+	if !utils.IsUUID(conversationID) {
+		utils.HandleError(ctx, http.StatusNotFound, "conversation not found", "conversation id must be a uuid")
+		return
+	}
+
+	conversation, err := m.mr.GetConversation(ctx, user.UserId, conversationID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrConversationNotFound) {
+			utils.HandleError(ctx, http.StatusNotFound, err.Error(), err.Error())
+			return
+		}
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, conversation)
+}
+
+// GetMessages returns the history of a conversation, newest first
+func (m *MessageHandler) GetMessages(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	conversationID := ctx.Param("id")
+	if !utils.IsUUID(conversationID) {
+		utils.HandleError(ctx, http.StatusNotFound, "conversation not found", "conversation id must be a uuid")
+		return
+	}
+
+	cursorTime, cursorID, err := pkg.ParseCursor(ctx.Query("cursor"))
+	if err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid cursor", err.Error())
+		return
+	}
+
+	page, err := m.mr.GetMessages(ctx, user.UserId, conversationID, cursorTime, cursorID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrConversationNotFound) {
+			utils.HandleError(ctx, http.StatusNotFound, err.Error(), err.Error())
+			return
+		}
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, page)
+}
+
+func (m *MessageHandler) SendMessage(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	conversationID := ctx.Param("id")
+	if !utils.IsUUID(conversationID) {
+		utils.HandleError(ctx, http.StatusNotFound, "conversation not found", "conversation id must be a uuid")
+		return
+	}
+
+	// Bind request body
+	var body models.SendMessage
+	if err := ctx.ShouldBind(&body); err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	if strings.TrimSpace(body.Body) == "" {
+		utils.HandleError(ctx, http.StatusBadRequest, "message cannot be empty", "empty message")
+		return
+	}
+	if utf8.RuneCountInString(body.Body) > maxMessageLength {
+		utils.HandleError(ctx, http.StatusBadRequest, fmt.Sprintf("message may be at most %d characters", maxMessageLength), "message too long")
+		return
+	}
+
+	msg, err := m.mr.SendMessage(ctx, user.UserId, conversationID, body.Body)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrConversationNotFound):
+			utils.HandleError(ctx, http.StatusNotFound, err.Error(), err.Error())
+		case errors.Is(err, repositories.ErrPrivateAccount):
+			utils.HandleError(ctx, http.StatusForbidden, "private accounts can only be messaged by their followers", err.Error())
+		default:
+			utils.Error(ctx, http.StatusInternalServerError, "failed to send message", err)
+		}
+		return
+	}
+
+	utils.Success(ctx, http.StatusCreated, msg)
+}
+
+// MarkRead moves the read receipt of the user in a conversation forward
+func (m *MessageHandler) MarkRead(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	conversationID := ctx.Param("id")
+	if !utils.IsUUID(conversationID) {
+		utils.HandleError(ctx, http.StatusNotFound, "conversation not found", "conversation id must be a uuid")
+		return
+	}
+
+	// The body is optional, without it everything is marked as read
+	var body models.MarkRead
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBind(&body); err != nil {
+			utils.HandleError(ctx, http.StatusBadRequest, "invalid request body", err.Error())
+			return
+		}
+	}
+	if body.MessageID != "" && !utils.IsUUID(body.MessageID) {
+		utils.HandleError(ctx, http.StatusNotFound, "message not found", "message id must be a uuid")
+		return
+	}
+
+	readAt, err := m.mr.MarkRead(ctx, user.UserId, conversationID, body.MessageID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrConversationNotFound), errors.Is(err, repositories.ErrMessageNotFound):
+			utils.HandleError(ctx, http.StatusNotFound, err.Error(), err.Error())
+		default:
+			utils.Error(ctx, http.StatusInternalServerError, "failed to mark as read", err)
+		}
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, gin.H{"conversation_id": conversationID, "last_read_at": readAt})
+}
+
+// StreamMessages pushes new messages and read receipts of the user's conversations
+// as server-sent events until the client disconnects
+func (m *MessageHandler) StreamMessages(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	reqCtx := ctx.Request.Context()
+	sub := m.mr.SubscribeMessages(reqCtx, user.UserId)
+	defer sub.Close()
+
+	// Wait for the subscription so no event sent after this point is missed
+	if _, err := sub.Receive(reqCtx); err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "failed to open message stream", err)
+		return
+	}
+	events := sub.Channel()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-reqCtx.Done():
+			return false
+		case msg, ok := <-events:
+			if !ok {
+				return false
+			}
+			var event models.MessageEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				log.Printf("Failed to decode message event: %v", err)
+				return true
+			}
+			ctx.SSEvent(event.Type, event)
+			return true
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return false
+			}
+			return true
+		}
+	})
+}
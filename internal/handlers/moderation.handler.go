@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/radifan9/social-media-backend/internal/repositories"
+	"github.com/radifan9/social-media-backend/internal/utils"
+	"github.com/radifan9/social-media-backend/pkg"
+)
+
+type ModerationHandler struct {
+	mr *repositories.ModerationRepository
+	ur *repositories.UserRepository
+}
+
+func NewModerationHandler(mr *repositories.ModerationRepository, ur *repositories.UserRepository) *ModerationHandler {
+	return &ModerationHandler{mr: mr, ur: ur}
+}
+
+func (m *ModerationHandler) CreateReport(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	// Bind request body
+	var body models.CreateReport
+	if err := ctx.ShouldBind(&body); err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	report, err := m.mr.CreateReport(ctx, user.UserId, body)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrPostNotFound):
+			utils.HandleError(ctx, http.StatusNotFound, "post not found", err.Error())
+		case errors.Is(err, repositories.ErrCommentNotFound):
+			utils.HandleError(ctx, http.StatusNotFound, "comment not found", err.Error())
+		case errors.Is(err, repositories.ErrUserNotFound):
+			utils.HandleError(ctx, http.StatusNotFound, "user not found", err.Error())
+		case errors.Is(err, repositories.ErrSelfAction):
+			utils.HandleError(ctx, http.StatusBadRequest, "you cannot report yourself", err.Error())
+		case errors.Is(err, repositories.ErrAlreadyReported):
+			utils.HandleError(ctx, http.StatusConflict, err.Error(), err.Error())
+		default:
+			utils.Error(ctx, http.StatusInternalServerError, "failed to create report", err)
+		}
+		return
+	}
+
+	utils.Success(ctx, http.StatusCreated, report)
+}
+
+func (m *ModerationHandler) ListReports(ctx *gin.Context) {
+	status := ctx.DefaultQuery("status", models.ReportOpen)
+	if status != models.ReportOpen && status != models.ReportClaimed && status != models.ReportResolved {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid status", "status must be open, claimed or resolved")
+		return
+	}
+
+	cursorTime, cursorID, err := pkg.ParseCursor(ctx.Query("cursor"))
+	if err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid cursor", err.Error())
+		return
+	}
+
+	page, err := m.mr.ListReports(ctx, status, cursorTime, cursorID)
+	if err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, page)
+}
+
+func (m *ModerationHandler) ClaimReport(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	reportID := ctx.Param("id")
+	if !utils.IsUUID(reportID) {
+		utils.HandleError(ctx, http.StatusNotFound, "report not found", "report id must be a uuid")
+		return
+	}
+
+	report, err := m.mr.ClaimReport(ctx, user.UserId, reportID)
+	if err != nil {
+		m.handleReportError(ctx, err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, report)
+}
+
+func (m *ModerationHandler) ResolveReport(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	reportID := ctx.Param("id")
+	if !utils.IsUUID(reportID) {
+		utils.HandleError(ctx, http.StatusNotFound, "report not found", "report id must be a uuid")
+		return
+	}
+
+	// Bind request body
+	var body models.ResolveReport
+	if err := ctx.ShouldBind(&body); err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	report, err := m.mr.ResolveReport(ctx, user.UserId, reportID, body)
+	if err != nil {
+		m.handleReportError(ctx, err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, report)
+}
+
+func (m *ModerationHandler) handleReportError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrReportNotFound):
+		utils.HandleError(ctx, http.StatusNotFound, err.Error(), err.Error())
+	case errors.Is(err, repositories.ErrReportUnavailable), errors.Is(err, repositories.ErrReportNotClaimed):
+		utils.HandleError(ctx, http.StatusConflict, err.Error(), err.Error())
+	case errors.Is(err, repositories.ErrInvalidResolution):
+		utils.HandleError(ctx, http.StatusBadRequest, err.Error(), err.Error())
+	default:
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+	}
+}
+
+func (m *ModerationHandler) SetAccountStatus(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	// The target may be given by id or handle
+	targetID, err := m.ur.ResolveUserID(ctx, ctx.Param("targetID"))
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			utils.HandleError(ctx, http.StatusNotFound, "user not found", err.Error())
+			return
+		}
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	// Bind request body
+	var body models.SetAccountStatus
+	if err := ctx.ShouldBind(&body); err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	status, err := m.mr.SetAccountStatus(ctx, user.UserId, targetID, body)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrUserNotFound):
+			utils.HandleError(ctx, http.StatusNotFound, "user not found", err.Error())
+		case errors.Is(err, repositories.ErrSelfAction), errors.Is(err, repositories.ErrSuspendUntil):
+			utils.HandleError(ctx, http.StatusBadRequest, err.Error(), err.Error())
+		default:
+			utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		}
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, status)
+}
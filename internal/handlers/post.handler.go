@@ -3,38 +3,96 @@ package handlers
 import (
 	"errors"
 	"fmt"
+	"mime/multipart"
 	"net/http"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
+	"github.com/radifan9/social-media-backend/internal/filters"
 	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/radifan9/social-media-backend/internal/ranking"
 	"github.com/radifan9/social-media-backend/internal/repositories"
 	"github.com/radifan9/social-media-backend/internal/utils"
 	"github.com/radifan9/social-media-backend/pkg"
 	"github.com/redis/go-redis/v9"
 )
 
+const (
+	maxAltTextLength    = 1000
+	maxPostLength       = 2000
+	maxCommentLength    = 1000
+	maxPollOptionLength = 80
+	minPollOptions      = 2
+	maxPollOptions      = 4
+	maxPollDuration     = 7 * 24 * time.Hour
+)
+
+var hashtagRe = regexp.MustCompile(`^[\p{L}\p{N}_]{1,100}$`)
+
 type PostHandler struct {
-	pr *repositories.PostRepository
-	ac *repositories.AuthCacheManager
+	pr      *repositories.PostRepository
+	ac      *repositories.AuthCacheManager
+	filter  filters.ContentFilter
+	weights ranking.Weights
 }
 
-func NewPostHandler(pr *repositories.PostRepository, rdb *redis.Client) *PostHandler {
+func NewPostHandler(pr *repositories.PostRepository, rdb *redis.Client, filter filters.ContentFilter, weights ranking.Weights) *PostHandler {
 	return &PostHandler{
-		pr: pr,
-		ac: repositories.NewAuthCacheManager(rdb),
+		pr:      pr,
+		ac:      repositories.NewAuthCacheManager(rdb),
+		filter:  filter,
+		weights: weights,
 	}
 }
 
+// checkText runs the length limit and the content filter on post or comment text.
+// It writes the error response itself and returns false when the text is refused.
+// holdReason is set when the text should be held for moderation
+func (p *PostHandler) checkText(ctx *gin.Context, text string, maxLength int) (holdReason *string, ok bool) {
+	if utf8.RuneCountInString(text) > maxLength {
+		utils.HandleError(ctx, http.StatusBadRequest, fmt.Sprintf("text may be at most %d characters", maxLength), "text too long")
+		return nil, false
+	}
+
+	verdict := p.filter.Check(ctx, text)
+	switch verdict.Action {
+	case filters.Reject:
+		utils.HandleErrorWithCode(ctx, http.StatusUnprocessableEntity, "content_rejected", verdict.Reason, "content rejected by filter")
+		return nil, false
+	case filters.Hold:
+		return &verdict.Reason, true
+	}
+	return nil, true
+}
+
 func (p *PostHandler) CreatePost(ctx *gin.Context) {
+	p.createPost(ctx, nil)
+}
+
+// QuotePost creates a post embedding the post in the URL, with the same form as CreatePost
+func (p *PostHandler) QuotePost(ctx *gin.Context) {
+	postID := ctx.Param("id")
+	if !utils.IsUUID(postID) {
+		utils.HandleError(ctx, http.StatusNotFound, "post not found", "post id must be a uuid")
+		return
+	}
+
+	p.createPost(ctx, &postID)
+}
+
+func (p *PostHandler) createPost(ctx *gin.Context, quotedPostID *string) {
 	// Get image from form-data
 	var body models.CreatePost
 	if err := ctx.ShouldBind(&body); err != nil {
 		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
 		return
 	}
+	body.QuotedPostID = quotedPostID
 
 	// Get the userID from token
 	claims, _ := ctx.Get("claims")
@@ -44,45 +102,185 @@ func (p *PostHandler) CreatePost(ctx *gin.Context) {
 		return
 	}
 
-	// Get Images if Exists
-	var imagePaths []string
-	if len(body.Images) > 0 {
-		for _, file := range body.Images {
-			if file == nil {
-				continue
-			}
-
-			// Validate extension
-			ext := filepath.Ext(file.Filename)
-			re := regexp.MustCompile(`(?i)\.(png|jpg|jpeg|webp)$`)
-			if !re.MatchString(ext) {
-				utils.HandleError(ctx, http.StatusBadRequest, "invalid file type", "only png, jpg, jpeg, webp allowed")
-				return
-			}
+	// A post needs text, media or a poll, the poll itself is validated below
+	if !hasPostContent(body.TextContent, body.Images) && len(body.PollOptions) == 0 {
+		utils.HandleError(ctx, http.StatusBadRequest, "post must have text, media or a poll", "empty post")
+		return
+	}
 
-			// Generate unique filename
-			filename := fmt.Sprintf("%d_images_%s%s", time.Now().UnixNano(), user.UserId, ext)
-			location := filepath.Join("public/post_images", filename)
+	if body.Visibility, ok = parseVisibility(ctx, body.Visibility); !ok {
+		return
+	}
 
-			// Save file
-			if err := ctx.SaveUploadedFile(file, location); err != nil {
-				utils.HandleError(ctx, http.StatusBadRequest, err.Error(), "failed to upload")
-				return
-			}
+	holdReason, ok := p.checkText(ctx, body.TextContent, maxPostLength)
+	if !ok {
+		return
+	}
 
-			imagePaths = append(imagePaths, filename)
+	if len(body.PollOptions) > 0 || body.PollClosesAt != "" {
+		var pollHoldReason *string
+		if body.Poll, pollHoldReason, ok = p.parsePoll(ctx, body.PollOptions, body.PollClosesAt); !ok {
+			return
+		}
+		if holdReason == nil {
+			holdReason = pollHoldReason
 		}
 	}
 
-	post, err := p.pr.CreatePost(ctx, user.UserId, body, imagePaths)
+	media, saved, ok := saveMedia(ctx, user.UserId, body.Images, body.AltTexts)
+	if !ok {
+		return
+	}
+
+	post, err := p.pr.CreatePost(ctx, user.UserId, body, media, holdReason)
 	if err != nil {
+		utils.RemoveFiles(saved...)
+		if errors.Is(err, repositories.ErrPostNotFound) {
+			utils.HandleError(ctx, http.StatusNotFound, "quoted post not found", err.Error())
+			return
+		}
 		utils.Error(ctx, http.StatusInternalServerError, "failed to create a post", err)
 		return
 	}
 
+	// Held posts are stored but only published once a moderator restores them
+	if post.HeldForReview {
+		utils.Success(ctx, http.StatusAccepted, post)
+		return
+	}
+
 	utils.Success(ctx, http.StatusOK, post)
 }
 
+// parsePoll validates the options and closing time of a poll, running every option
+// through the content filter. It writes the error response itself and returns false
+// when the poll is refused
+func (p *PostHandler) parsePoll(ctx *gin.Context, options []string, closesAt string) (poll *models.NewPoll, holdReason *string, ok bool) {
+	if len(options) < minPollOptions || len(options) > maxPollOptions {
+		utils.HandleError(ctx, http.StatusBadRequest, fmt.Sprintf("a poll needs %d to %d options", minPollOptions, maxPollOptions), "invalid poll options")
+		return nil, nil, false
+	}
+
+	poll = &models.NewPoll{Options: make([]string, 0, len(options))}
+	for _, option := range options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			utils.HandleError(ctx, http.StatusBadRequest, "poll options cannot be empty", "empty poll option")
+			return nil, nil, false
+		}
+		if slices.Contains(poll.Options, option) {
+			utils.HandleError(ctx, http.StatusBadRequest, "poll options must be different", "duplicate poll option")
+			return nil, nil, false
+		}
+
+		optionHoldReason, ok := p.checkText(ctx, option, maxPollOptionLength)
+		if !ok {
+			return nil, nil, false
+		}
+		if holdReason == nil {
+			holdReason = optionHoldReason
+		}
+		poll.Options = append(poll.Options, option)
+	}
+
+	var err error
+	if poll.ClosesAt, err = time.Parse(time.RFC3339, closesAt); err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "poll-closes-at must be an RFC 3339 time", err.Error())
+		return nil, nil, false
+	}
+	if !poll.ClosesAt.After(time.Now()) || poll.ClosesAt.After(time.Now().Add(maxPollDuration)) {
+		utils.HandleError(ctx, http.StatusBadRequest, "poll must close within the next 7 days", "invalid poll closing time")
+		return nil, nil, false
+	}
+
+	return poll, holdReason, true
+}
+
+// parseVisibility validates the audience of a post, an empty value means public.
+// It writes the error response itself and returns false when the value is unknown
+func parseVisibility(ctx *gin.Context, visibility string) (string, bool) {
+	switch visibility {
+	case "":
+		return models.VisibilityPublic, true
+	case models.VisibilityPublic, models.VisibilityFollowers, models.VisibilityCloseFriends, models.VisibilityMentioned:
+		return visibility, true
+	default:
+		utils.HandleError(ctx, http.StatusBadRequest, "visibility must be public, followers, close_friends or mentioned", "invalid visibility")
+		return "", false
+	}
+}
+
+func hasPostContent(text string, images []*multipart.FileHeader) bool {
+	if strings.TrimSpace(text) != "" {
+		return true
+	}
+	for _, file := range images {
+		if file != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// saveMedia validates the uploaded files and writes them to disk.
+// It writes the error response itself and returns false when a file is refused.
+// The caller removes saved if storing the post fails afterwards
+func saveMedia(ctx *gin.Context, userID string, images []*multipart.FileHeader, altTexts []string) (media []models.PostImage, saved []string, ok bool) {
+	// Validate every file before anything touches the disk
+	var files []*multipart.FileHeader
+	for i, file := range images {
+		if file == nil {
+			continue
+		}
+
+		ext := filepath.Ext(file.Filename)
+		mediaType, ok := pkg.DetectMediaType(ext)
+		if !ok {
+			utils.HandleError(ctx, http.StatusBadRequest, "invalid file type", "only png, jpg, jpeg, webp, gif, mp4, webm allowed")
+			return nil, nil, false
+		}
+		if file.Size > pkg.MaxMediaSize(mediaType) {
+			utils.HandleError(ctx, http.StatusBadRequest, fmt.Sprintf("%s exceeds the %d MB limit", file.Filename, pkg.MaxMediaSize(mediaType)>>20), "file too large")
+			return nil, nil, false
+		}
+
+		// Alt texts are matched to images by their index in the form
+		var altText *string
+		if i < len(altTexts) {
+			if text := strings.TrimSpace(altTexts[i]); text != "" {
+				if utf8.RuneCountInString(text) > maxAltTextLength {
+					utils.HandleError(ctx, http.StatusBadRequest, fmt.Sprintf("alt text may be at most %d characters", maxAltTextLength), "alt text too long")
+					return nil, nil, false
+				}
+				altText = &text
+			}
+		}
+
+		// Generate unique filename
+		filename := fmt.Sprintf("%d_images_%s%s", time.Now().UnixNano(), userID, ext)
+		files = append(files, file)
+		media = append(media, models.PostImage{
+			MediaURL:  filename,
+			MediaType: mediaType,
+			SizeBytes: file.Size,
+			AltText:   altText,
+		})
+	}
+
+	// Save files, removing the ones already written if a later step fails
+	for i, file := range files {
+		location := filepath.Join("public/post_images", media[i].MediaURL)
+		if err := ctx.SaveUploadedFile(file, location); err != nil {
+			utils.RemoveFiles(saved...)
+			utils.HandleError(ctx, http.StatusBadRequest, err.Error(), "failed to upload")
+			return nil, nil, false
+		}
+		saved = append(saved, location)
+	}
+
+	return media, saved, true
+}
+
 func (p *PostHandler) GetFollowingFeed(ctx *gin.Context) {
 	// Get the userID from token
 	claims, _ := ctx.Get("claims")
@@ -101,6 +299,31 @@ func (p *PostHandler) GetFollowingFeed(ctx *gin.Context) {
 	utils.Success(ctx, http.StatusOK, posts)
 }
 
+// GetForYouFeed returns the ranked feed, paged with a rank cursor
+func (p *PostHandler) GetForYouFeed(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	cursorScore, cursorID, err := pkg.ParseScoreCursor(ctx.Query("cursor"))
+	if err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid cursor", err.Error())
+		return
+	}
+
+	page, err := p.pr.GetForYouFeed(ctx, user.UserId, p.weights, cursorScore, cursorID)
+	if err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, page)
+}
+
 func (p *PostHandler) LikePost(ctx *gin.Context) {
 	// Get the userID from token
 	claims, _ := ctx.Get("claims")
@@ -120,7 +343,7 @@ func (p *PostHandler) LikePost(ctx *gin.Context) {
 	// Like the post
 	like, err := p.pr.LikePost(ctx, user.UserId, body.PostID)
 	if err != nil {
-		if err.Error() == "post not found" {
+		if errors.Is(err, repositories.ErrPostNotFound) {
 			utils.HandleError(ctx, http.StatusNotFound, "post not found", err.Error())
 			return
 		}
@@ -147,12 +370,503 @@ func (p *PostHandler) AddComment(ctx *gin.Context) {
 		return
 	}
 
+	if strings.TrimSpace(body.Comment) == "" {
+		utils.HandleError(ctx, http.StatusBadRequest, "comment cannot be empty", "empty comment")
+		return
+	}
+
+	holdReason, ok := p.checkText(ctx, body.Comment, maxCommentLength)
+	if !ok {
+		return
+	}
+
 	// Add comment
-	comment, err := p.pr.AddComment(ctx, user.UserId, body.PostID, body.Comment)
+	comment, err := p.pr.AddComment(ctx, user.UserId, body.PostID, body.Comment, holdReason)
 	if err != nil {
+		if errors.Is(err, repositories.ErrPostNotFound) {
+			utils.HandleError(ctx, http.StatusNotFound, "post not found", err.Error())
+			return
+		}
 		utils.Error(ctx, http.StatusInternalServerError, "failed to add comment", err)
 		return
 	}
 
+	if comment.HeldForReview {
+		utils.Success(ctx, http.StatusAccepted, comment)
+		return
+	}
+
 	utils.Success(ctx, http.StatusCreated, comment)
 }
+
+func (p *PostHandler) GetPostDetail(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	postID := ctx.Param("id")
+	if !utils.IsUUID(postID) {
+		utils.HandleError(ctx, http.StatusNotFound, "post not found", "post id must be a uuid")
+		return
+	}
+
+	post, err := p.pr.GetPostDetail(ctx, user.UserId, postID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPostNotFound) {
+			utils.HandleError(ctx, http.StatusNotFound, "post not found", err.Error())
+			return
+		}
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, post)
+}
+
+func (p *PostHandler) GetHashtagPosts(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	tag := strings.TrimPrefix(ctx.Param("tag"), "#")
+	if !hashtagRe.MatchString(tag) {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid hashtag", "hashtag must be letters, digits or underscore")
+		return
+	}
+
+	cursorTime, cursorID, err := pkg.ParseCursor(ctx.Query("cursor"))
+	if err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid cursor", err.Error())
+		return
+	}
+
+	page, err := p.pr.GetHashtagPosts(ctx, user.UserId, tag, cursorTime, cursorID)
+	if err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, page)
+}
+
+func (p *PostHandler) Repost(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	postID := ctx.Param("id")
+	if !utils.IsUUID(postID) {
+		utils.HandleError(ctx, http.StatusNotFound, "post not found", "post id must be a uuid")
+		return
+	}
+
+	repost, err := p.pr.Repost(ctx, user.UserId, postID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrPostNotFound):
+			utils.HandleError(ctx, http.StatusNotFound, "post not found", err.Error())
+		case errors.Is(err, repositories.ErrRepostPrivate), errors.Is(err, repositories.ErrRepostRestricted):
+			utils.HandleError(ctx, http.StatusForbidden, err.Error(), "repost refused")
+		case errors.Is(err, repositories.ErrAlreadyReposted):
+			utils.HandleError(ctx, http.StatusConflict, err.Error(), "repost refused")
+		default:
+			utils.Error(ctx, http.StatusInternalServerError, "failed to repost", err)
+		}
+		return
+	}
+
+	utils.Success(ctx, http.StatusCreated, repost)
+}
+
+func (p *PostHandler) UndoRepost(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	postID := ctx.Param("id")
+	if !utils.IsUUID(postID) {
+		utils.HandleError(ctx, http.StatusNotFound, "repost not found", "post id must be a uuid")
+		return
+	}
+
+	if err := p.pr.UndoRepost(ctx, user.UserId, postID); err != nil {
+		if errors.Is(err, repositories.ErrRepostNotFound) {
+			utils.HandleError(ctx, http.StatusNotFound, err.Error(), "undo repost failed")
+			return
+		}
+		utils.Error(ctx, http.StatusInternalServerError, "failed to undo repost", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, gin.H{"message": "repost removed"})
+}
+
+func (p *PostHandler) BookmarkPost(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	postID := ctx.Param("id")
+	if !utils.IsUUID(postID) {
+		utils.HandleError(ctx, http.StatusNotFound, "post not found", "post id must be a uuid")
+		return
+	}
+
+	if err := p.pr.BookmarkPost(ctx, user.UserId, postID); err != nil {
+		if errors.Is(err, repositories.ErrPostNotFound) {
+			utils.HandleError(ctx, http.StatusNotFound, "post not found", err.Error())
+			return
+		}
+		utils.Error(ctx, http.StatusInternalServerError, "failed to bookmark post", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, gin.H{"post_id": postID, "bookmarked": true})
+}
+
+func (p *PostHandler) RemoveBookmark(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	postID := ctx.Param("id")
+	if !utils.IsUUID(postID) {
+		utils.HandleError(ctx, http.StatusNotFound, "post not found", "post id must be a uuid")
+		return
+	}
+
+	if err := p.pr.RemoveBookmark(ctx, user.UserId, postID); err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "failed to remove bookmark", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, gin.H{"post_id": postID, "bookmarked": false})
+}
+
+func (p *PostHandler) GetBookmarks(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	cursorTime, cursorID, err := pkg.ParseCursor(ctx.Query("cursor"))
+	if err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid cursor", err.Error())
+		return
+	}
+
+	page, err := p.pr.GetBookmarks(ctx, user.UserId, cursorTime, cursorID)
+	if err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, page)
+}
+
+// GetTrending returns the top posts and hashtags of the last hour or day (window=1h or 24h, default 24h)
+func (p *PostHandler) GetTrending(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	trending, err := p.pr.GetTrending(ctx, user.UserId, ctx.DefaultQuery("window", "24h"))
+	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidTrendingWindow) {
+			utils.HandleError(ctx, http.StatusBadRequest, err.Error(), "invalid trending window")
+			return
+		}
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, trending)
+}
+
+// parsePublishAt reads the publish time of a scheduled post, it has to lie in the future.
+// It writes the error response itself and returns false when the time is refused
+func parsePublishAt(ctx *gin.Context, value string) (*time.Time, bool) {
+	publishAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "publish_at must be an RFC 3339 time", err.Error())
+		return nil, false
+	}
+	if !publishAt.After(time.Now()) {
+		utils.HandleError(ctx, http.StatusBadRequest, "publish_at must be in the future", "publish time in the past")
+		return nil, false
+	}
+	return &publishAt, true
+}
+
+func draftMedia(media []models.PostImage) []models.DraftMedia {
+	drafts := make([]models.DraftMedia, 0, len(media))
+	for _, m := range media {
+		drafts = append(drafts, models.DraftMedia{
+			MediaURL:  m.MediaURL,
+			MediaType: m.MediaType,
+			SizeBytes: m.SizeBytes,
+			AltText:   m.AltText,
+		})
+	}
+	return drafts
+}
+
+// CreateDraft saves a post for later, with the same form as CreatePost.
+// With publish_at set it is published by the scheduler at that time
+func (p *PostHandler) CreateDraft(ctx *gin.Context) {
+	var body models.SaveDraft
+	if err := ctx.ShouldBind(&body); err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	if !hasPostContent(body.TextContent, body.Images) {
+		utils.HandleError(ctx, http.StatusBadRequest, "draft must have text or media", "empty draft")
+		return
+	}
+
+	if body.Visibility, ok = parseVisibility(ctx, body.Visibility); !ok {
+		return
+	}
+
+	var publishAt *time.Time
+	if body.PublishAt != "" {
+		if publishAt, ok = parsePublishAt(ctx, body.PublishAt); !ok {
+			return
+		}
+	}
+
+	// Text that needs review is only held once the post is published
+	if _, ok := p.checkText(ctx, body.TextContent, maxPostLength); !ok {
+		return
+	}
+
+	media, saved, ok := saveMedia(ctx, user.UserId, body.Images, body.AltTexts)
+	if !ok {
+		return
+	}
+
+	draft, err := p.pr.CreateDraft(ctx, user.UserId, body.TextContent, body.Visibility, draftMedia(media), publishAt)
+	if err != nil {
+		utils.RemoveFiles(saved...)
+		utils.Error(ctx, http.StatusInternalServerError, "failed to save draft", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusCreated, draft)
+}
+
+// GetDrafts lists the drafts and scheduled posts of the user
+func (p *PostHandler) GetDrafts(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	cursorTime, cursorID, err := pkg.ParseCursor(ctx.Query("cursor"))
+	if err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid cursor", err.Error())
+		return
+	}
+
+	page, err := p.pr.GetDrafts(ctx, user.UserId, cursorTime, cursorID)
+	if err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, page)
+}
+
+// EditDraft changes the text, media, visibility or publish time of a draft.
+// An empty publish_at unschedules the post, keeping it as a draft
+func (p *PostHandler) EditDraft(ctx *gin.Context) {
+	draftID := ctx.Param("id")
+	if !utils.IsUUID(draftID) {
+		utils.HandleError(ctx, http.StatusNotFound, "draft not found", "draft id must be a uuid")
+		return
+	}
+
+	var body models.EditDraft
+	if err := ctx.ShouldBind(&body); err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	changes := models.DraftChanges{TextContent: body.TextContent}
+	if body.Visibility != nil {
+		visibility, ok := parseVisibility(ctx, *body.Visibility)
+		if !ok {
+			return
+		}
+		changes.Visibility = &visibility
+	}
+	if body.PublishAt != nil {
+		changes.SetPublishAt = true
+		if *body.PublishAt != "" {
+			if changes.PublishAt, ok = parsePublishAt(ctx, *body.PublishAt); !ok {
+				return
+			}
+		}
+	}
+
+	if body.TextContent != nil {
+		if _, ok := p.checkText(ctx, *body.TextContent, maxPostLength); !ok {
+			return
+		}
+	}
+
+	var saved []string
+	if hasPostContent("", body.Images) {
+		var media []models.PostImage
+		if media, saved, ok = saveMedia(ctx, user.UserId, body.Images, body.AltTexts); !ok {
+			return
+		}
+		changes.Media = draftMedia(media)
+		changes.ReplaceMedia = true
+	}
+
+	draft, oldFiles, err := p.pr.EditDraft(ctx, user.UserId, draftID, changes)
+	if err != nil {
+		utils.RemoveFiles(saved...)
+		switch {
+		case errors.Is(err, repositories.ErrDraftNotFound):
+			utils.HandleError(ctx, http.StatusNotFound, err.Error(), "draft not found")
+		case errors.Is(err, repositories.ErrEmptyDraft):
+			utils.HandleError(ctx, http.StatusBadRequest, err.Error(), "empty draft")
+		default:
+			utils.Error(ctx, http.StatusInternalServerError, "failed to edit draft", err)
+		}
+		return
+	}
+
+	// The replaced media is no longer referenced
+	utils.RemoveFiles(oldFiles...)
+
+	utils.Success(ctx, http.StatusOK, draft)
+}
+
+// CancelDraft deletes a draft, a scheduled post is not published anymore
+func (p *PostHandler) CancelDraft(ctx *gin.Context) {
+	draftID := ctx.Param("id")
+	if !utils.IsUUID(draftID) {
+		utils.HandleError(ctx, http.StatusNotFound, "draft not found", "draft id must be a uuid")
+		return
+	}
+
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	files, err := p.pr.DeleteDraft(ctx, user.UserId, draftID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrDraftNotFound) {
+			utils.HandleError(ctx, http.StatusNotFound, err.Error(), "draft not found")
+			return
+		}
+		utils.Error(ctx, http.StatusInternalServerError, "failed to delete draft", err)
+		return
+	}
+
+	utils.RemoveFiles(files...)
+
+	utils.Success(ctx, http.StatusOK, gin.H{"draft_id": draftID, "deleted": true})
+}
+
+// VotePoll votes for one option of the poll of a post and returns the results
+func (p *PostHandler) VotePoll(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	postID := ctx.Param("id")
+	if !utils.IsUUID(postID) {
+		utils.HandleError(ctx, http.StatusNotFound, "post not found", "post id must be a uuid")
+		return
+	}
+
+	var body models.PollVote
+	if err := ctx.ShouldBind(&body); err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+	if !utils.IsUUID(body.OptionID) {
+		utils.HandleError(ctx, http.StatusBadRequest, repositories.ErrPollOptionNotFound.Error(), "option id must be a uuid")
+		return
+	}
+
+	poll, err := p.pr.VotePoll(ctx, user.UserId, postID, body.OptionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrPostNotFound), errors.Is(err, repositories.ErrPollNotFound):
+			utils.HandleError(ctx, http.StatusNotFound, err.Error(), "vote refused")
+		case errors.Is(err, repositories.ErrPollOptionNotFound):
+			utils.HandleError(ctx, http.StatusBadRequest, err.Error(), "vote refused")
+		case errors.Is(err, repositories.ErrAlreadyVoted), errors.Is(err, repositories.ErrPollClosed):
+			utils.HandleError(ctx, http.StatusConflict, err.Error(), "vote refused")
+		default:
+			utils.Error(ctx, http.StatusInternalServerError, "failed to vote", err)
+		}
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, poll)
+}
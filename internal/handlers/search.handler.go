@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/radifan9/social-media-backend/internal/repositories"
+	"github.com/radifan9/social-media-backend/internal/utils"
+	"github.com/radifan9/social-media-backend/pkg"
+)
+
+const maxSearchQueryLength = 100
+
+type SearchHandler struct {
+	sr *repositories.SearchRepository
+}
+
+func NewSearchHandler(sr *repositories.SearchRepository) *SearchHandler {
+	return &SearchHandler{sr: sr}
+}
+
+func (s *SearchHandler) Search(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", errors.New("cannot cast into pkg.claims"))
+		return
+	}
+
+	var query models.SearchQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "query parameter q is required", err.Error())
+		return
+	}
+
+	query.Query = strings.TrimSpace(query.Query)
+	if query.Query == "" || utf8.RuneCountInString(query.Query) > maxSearchQueryLength {
+		utils.HandleError(ctx, http.StatusBadRequest, "q must be between 1 and 100 characters", "invalid search query")
+		return
+	}
+
+	cursorRank, cursorID, err := pkg.ParseRankCursor(query.Cursor)
+	if err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid cursor", err.Error())
+		return
+	}
+
+	var page models.SearchPage
+	switch query.Type {
+	case "", models.SearchPosts:
+		page, err = s.sr.SearchPosts(ctx, user.UserId, query.Query, cursorRank, cursorID)
+	case models.SearchUsers:
+		page, err = s.sr.SearchUsers(ctx, user.UserId, strings.TrimPrefix(query.Query, "@"), cursorRank, cursorID)
+	default:
+		utils.HandleError(ctx, http.StatusBadRequest, "type must be posts or users", "invalid search type")
+		return
+	}
+	if err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, page)
+}
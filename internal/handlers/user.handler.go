@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -19,14 +20,18 @@ import (
 )
 
 type UserHandler struct {
-	ur *repositories.UserRepository
-	ac *repositories.AuthCacheManager
+	ur     *repositories.UserRepository
+	pr     *repositories.PostRepository
+	ac     *repositories.AuthCacheManager
+	mailer pkg.Mailer
 }
 
-func NewUserHandler(ur *repositories.UserRepository, rdb *redis.Client) *UserHandler {
+func NewUserHandler(ur *repositories.UserRepository, pr *repositories.PostRepository, rdb *redis.Client) *UserHandler {
 	return &UserHandler{
-		ur: ur,
-		ac: repositories.NewAuthCacheManager(rdb),
+		ur:     ur,
+		pr:     pr,
+		ac:     repositories.NewAuthCacheManager(rdb),
+		mailer: pkg.NewMailerFromEnv(),
 	}
 }
 
@@ -40,6 +45,13 @@ func (u *UserHandler) Register(ctx *gin.Context) {
 		return
 	}
 
+	if user.Username != "" {
+		if err := utils.ValidateUsername(user.Username); err != nil {
+			utils.HandleError(ctx, http.StatusBadRequest, err.Error(), "invalid username")
+			return
+		}
+	}
+
 	hashCfg := pkg.NewHashConfig()
 	hashCfg.UseRecommended()
 	hashedPassword, err := hashCfg.GenHash(user.Password)
@@ -48,10 +60,14 @@ func (u *UserHandler) Register(ctx *gin.Context) {
 		return
 	}
 
-	newUser, err := u.ur.CreateUser(ctx, user.Email, hashedPassword)
+	newUser, err := u.ur.CreateUser(ctx, user.Email, hashedPassword, user.Username)
 	if err != nil {
 		log.Println("error : ", err)
-		utils.HandleError(ctx, http.StatusConflict, "failed to register", err.Error())
+		if errors.Is(err, repositories.ErrUsernameTaken) || errors.Is(err, repositories.ErrEmailTaken) {
+			utils.HandleError(ctx, http.StatusConflict, err.Error(), "failed to register")
+			return
+		}
+		utils.HandleError(ctx, http.StatusInternalServerError, "failed to register", err.Error())
 		return
 	}
 
@@ -113,8 +129,32 @@ func (u *UserHandler) Login(ctx *gin.Context) {
 		return
 	}
 
+	// Suspended and banned accounts cannot get a token
+	switch {
+	case userCred.Status == models.StatusBanned:
+		utils.HandleErrorWithCode(ctx, http.StatusForbidden, "account_banned", "this account has been banned", "login refused for banned account")
+		return
+	case userCred.Status == models.StatusSuspended && userCred.SuspendedUntil != nil && userCred.SuspendedUntil.After(time.Now()):
+		utils.HandleErrorWithCode(ctx, http.StatusForbidden, "account_suspended",
+			fmt.Sprintf("this account is suspended until %s", userCred.SuspendedUntil.UTC().Format(time.RFC3339)),
+			"login refused for suspended account")
+		return
+	}
+
+	// Logging in during the grace period cancels a pending deletion
+	if userCred.DeletedAt != nil {
+		if err := u.ur.RestoreAccount(ctx, infoUser.Id); err != nil {
+			if errors.Is(err, repositories.ErrAccountDeleted) {
+				utils.HandleErrorWithCode(ctx, http.StatusForbidden, "account_deleted", err.Error(), "login refused for deleted account")
+				return
+			}
+			utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+			return
+		}
+	}
+
 	// Jika match, maka buatkan jwt dan kirim via response
-	claims := pkg.NewJWTClaims(infoUser.Id)
+	claims := pkg.NewJWTClaims(infoUser.Id, userCred.Role)
 	jwtToken, err := claims.GenToken()
 	if err != nil {
 		log.Println("Internal Server Error.\nCause: ", err.Error())
@@ -197,9 +237,16 @@ func (u *UserHandler) EditProfile(ctx *gin.Context) {
 		return
 	}
 
+	if body.Username != "" {
+		if err := utils.ValidateUsername(body.Username); err != nil {
+			utils.HandleError(ctx, http.StatusBadRequest, err.Error(), "invalid username")
+			return
+		}
+	}
+
 	// Dari postman harus ambil gambar baru
-	file := body.Avatar
-	if file != nil {
+	var avatarPath, location string
+	if file := body.Avatar; file != nil {
 		ext := filepath.Ext(file.Filename)
 		re := regexp.MustCompile(`(?i)\.(png|jpg|jpeg|webp)$`)
 		if !re.MatchString(ext) {
@@ -207,30 +254,33 @@ func (u *UserHandler) EditProfile(ctx *gin.Context) {
 			return
 		}
 
-		filename := fmt.Sprintf("%d_images_%s%s", time.Now().UnixNano(), user.UserId, ext)
-		location := filepath.Join("public/avatars", filename)
+		avatarPath = fmt.Sprintf("%d_images_%s%s", time.Now().UnixNano(), user.UserId, ext)
+		location = filepath.Join("public/avatars", avatarPath)
 
 		if err := ctx.SaveUploadedFile(file, location); err != nil {
 			utils.HandleError(ctx, http.StatusBadRequest, err.Error(), "failed to upload")
 			return
 		}
+	}
 
-		// Update profile with new image
-		editedProfile, err := u.ur.EditProfile(ctx.Request.Context(), user.UserId, body, filename)
-		if err != nil {
-			utils.HandleError(ctx, http.StatusInternalServerError, err.Error(), "cannot edit user profile")
-			return
+	editedProfile, oldAvatar, err := u.ur.EditProfile(ctx.Request.Context(), user.UserId, body, avatarPath)
+	if err != nil {
+		// Drop the new avatar so it does not linger unreferenced
+		utils.RemoveFiles(location)
+		switch {
+		case errors.Is(err, repositories.ErrUsernameTaken):
+			utils.Error(ctx, http.StatusConflict, err.Error(), err)
+		case errors.Is(err, repositories.ErrUsernameCooldown):
+			utils.Error(ctx, http.StatusBadRequest, err.Error(), err)
+		default:
+			utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
 		}
-
-		utils.HandleResponse(ctx, http.StatusOK, models.SuccessResponse{Success: true, Status: http.StatusOK, Data: editedProfile})
 		return
 	}
 
-	// If no image uploaded, just update profile without image
-	editedProfile, err := u.ur.EditProfile(ctx.Request.Context(), user.UserId, body, "")
-	if err != nil {
-		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
-		return
+	// The previous avatar is no longer referenced once a new one is stored
+	if avatarPath != "" && oldAvatar != "" && oldAvatar != avatarPath {
+		utils.RemoveFiles(filepath.Join("public/avatars", filepath.Base(oldAvatar)))
 	}
 
 	utils.Success(ctx, http.StatusOK, editedProfile)
@@ -245,18 +295,485 @@ func (u *UserHandler) FollowUser(ctx *gin.Context) {
 		return
 	}
 
-	// Get follow target
-	targetID := ctx.Param("targetID")
+	// Get follow target, either a user id or a handle
+	targetID, ok := u.resolveTarget(ctx)
+	if !ok {
+		return
+	}
 
-	if err := u.ur.FollowUser(ctx, user.UserId, targetID); err != nil {
+	requested, err := u.ur.FollowUser(ctx, user.UserId, targetID)
+	if err != nil {
 		switch {
+		case errors.Is(err, repositories.ErrSelfAction):
+			utils.Error(ctx, http.StatusBadRequest, err.Error(), err)
+		case errors.Is(err, repositories.ErrUserNotFound):
+			utils.Error(ctx, http.StatusNotFound, "user not found", err)
 		case errors.Is(err, repositories.ErrAlreadyFollowed):
 			utils.Error(ctx, http.StatusConflict, "you already follow this user.", err)
+		case errors.Is(err, repositories.ErrAlreadyRequested):
+			utils.Error(ctx, http.StatusConflict, "you already requested to follow this user.", err)
 		default:
 			utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
 		}
 		return
 	}
 
+	// Private accounts have to approve the request first
+	if requested {
+		utils.Success(ctx, http.StatusAccepted, gin.H{"status": "requested"})
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, gin.H{"status": "following"})
+}
+
+func (u *UserHandler) GetFollowRequests(ctx *gin.Context) {
+	u.listRelation(ctx, u.ur.GetFollowRequests)
+}
+
+func (u *UserHandler) ApproveFollowRequest(ctx *gin.Context) {
+	u.followRequestAction(ctx, u.ur.ApproveFollowRequest)
+}
+
+func (u *UserHandler) RejectFollowRequest(ctx *gin.Context) {
+	u.followRequestAction(ctx, u.ur.RejectFollowRequest)
+}
+
+// followRequestAction answers the pending request sent by the requesterID route param
+func (u *UserHandler) followRequestAction(ctx *gin.Context, action func(ctx context.Context, userID, requesterID string) error) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.HandleError(ctx, http.StatusInternalServerError, "internal server error", "cannot cast into pkg.claims")
+		return
+	}
+
+	requesterID := ctx.Param("requesterID")
+	if !utils.IsUUID(requesterID) {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid requester id", "requester id must be a uuid")
+		return
+	}
+
+	if err := action(ctx, user.UserId, requesterID); err != nil {
+		if errors.Is(err, repositories.ErrFollowRequestNotFound) {
+			utils.Error(ctx, http.StatusNotFound, err.Error(), err)
+			return
+		}
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, nil)
+}
+
+func (u *UserHandler) GetProfileByHandle(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.HandleError(ctx, http.StatusInternalServerError, "internal server error", "cannot cast into pkg.claims")
+		return
+	}
+
+	username := strings.TrimPrefix(ctx.Param("username"), "@")
+
+	profile, err := u.ur.GetProfileByUsername(ctx, user.UserId, username)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			utils.Error(ctx, http.StatusNotFound, "user not found", err)
+			return
+		}
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, profile)
+}
+
+// resolveTarget reads the targetID route param, which may be a user id or a handle.
+// It writes the error response itself and returns false when the user does not exist
+func (u *UserHandler) resolveTarget(ctx *gin.Context) (string, bool) {
+	targetID, err := u.ur.ResolveUserID(ctx, ctx.Param("targetID"))
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			utils.Error(ctx, http.StatusNotFound, "user not found", err)
+			return "", false
+		}
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return "", false
+	}
+	return targetID, true
+}
+
+func (u *UserHandler) BlockUser(ctx *gin.Context) {
+	u.relationAction(ctx, u.ur.BlockUser)
+}
+
+func (u *UserHandler) UnblockUser(ctx *gin.Context) {
+	u.relationAction(ctx, u.ur.UnblockUser)
+}
+
+func (u *UserHandler) MuteUser(ctx *gin.Context) {
+	u.relationAction(ctx, u.ur.MuteUser)
+}
+
+func (u *UserHandler) UnmuteUser(ctx *gin.Context) {
+	u.relationAction(ctx, u.ur.UnmuteUser)
+}
+
+// relationAction runs a block/mute style action from the caller towards the route target
+func (u *UserHandler) relationAction(ctx *gin.Context, action func(ctx context.Context, userID, targetID string) error) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.HandleError(ctx, http.StatusInternalServerError, "internal server error", "cannot cast into pkg.claims")
+		return
+	}
+
+	targetID, ok := u.resolveTarget(ctx)
+	if !ok {
+		return
+	}
+
+	if err := action(ctx, user.UserId, targetID); err != nil {
+		if errors.Is(err, repositories.ErrSelfAction) {
+			utils.Error(ctx, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			utils.Error(ctx, http.StatusNotFound, "user not found", err)
+			return
+		}
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
 	utils.Success(ctx, http.StatusOK, nil)
 }
+
+func (u *UserHandler) AddCloseFriend(ctx *gin.Context) {
+	u.relationAction(ctx, u.ur.AddCloseFriend)
+}
+
+func (u *UserHandler) RemoveCloseFriend(ctx *gin.Context) {
+	u.relationAction(ctx, u.ur.RemoveCloseFriend)
+}
+
+func (u *UserHandler) GetBlockedUsers(ctx *gin.Context) {
+	u.listRelation(ctx, u.ur.GetBlockedUsers)
+}
+
+func (u *UserHandler) GetMutedUsers(ctx *gin.Context) {
+	u.listRelation(ctx, u.ur.GetMutedUsers)
+}
+
+func (u *UserHandler) GetCloseFriends(ctx *gin.Context) {
+	u.listRelation(ctx, u.ur.GetCloseFriends)
+}
+
+func (u *UserHandler) listRelation(ctx *gin.Context, list func(ctx context.Context, userID string) ([]models.UserSummary, error)) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.HandleError(ctx, http.StatusInternalServerError, "internal server error", "cannot cast into pkg.claims")
+		return
+	}
+
+	users, err := list(ctx, user.UserId)
+	if err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, users)
+}
+
+// DeleteAccount schedules the caller's account for deletion after confirming their password
+func (u *UserHandler) DeleteAccount(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.HandleError(ctx, http.StatusInternalServerError, "internal server error", "cannot cast into pkg.claims")
+		return
+	}
+
+	var body models.DeleteAccount
+	if err := ctx.ShouldBind(&body); err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "password is required", err.Error())
+		return
+	}
+
+	userCred, err := u.ur.GetPasswordFromID(ctx, user.UserId)
+	if err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	hashCfg := pkg.NewHashConfig()
+	isMatched, err := hashCfg.CompareHashAndPassword(body.Password, userCred.Password)
+	if err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if !isMatched {
+		utils.HandleError(ctx, http.StatusForbidden, "wrong password", "password mismatch on account deletion")
+		return
+	}
+
+	deletion, err := u.ur.DeleteAccount(ctx, user.UserId)
+	if err != nil {
+		if errors.Is(err, repositories.ErrAccountDeleted) {
+			utils.HandleErrorWithCode(ctx, http.StatusConflict, "account_deleted", "account is already scheduled for deletion", "repeated account deletion")
+			return
+		}
+		utils.Error(ctx, http.StatusInternalServerError, "failed to delete account", err)
+		return
+	}
+
+	// Sign the user out everywhere
+	if err := u.ac.BlacklistUserTokens(ctx.Request.Context(), user.UserId, time.Now(), pkg.TokenLifetime); err != nil {
+		log.Printf("Failed to revoke tokens of deleted user %s: %v", user.UserId, err)
+	}
+
+	utils.Success(ctx, http.StatusOK, deletion)
+}
+
+// ExportData streams a ZIP with a JSON file per kind of data and the user's uploads under media/
+func (u *UserHandler) ExportData(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.HandleError(ctx, http.StatusInternalServerError, "internal server error", "cannot cast into pkg.claims")
+		return
+	}
+
+	export, err := u.ur.ExportUserData(ctx, user.UserId)
+	if err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "failed to export data", err)
+		return
+	}
+
+	filename := fmt.Sprintf("sosmed-export-%s.zip", time.Now().UTC().Format("20060102"))
+	ctx.Header("Content-Type", "application/zip")
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	ctx.Status(http.StatusOK)
+
+	// Headers are sent once writing starts, so later failures can only be logged
+	if err := utils.WriteExportZip(ctx.Writer, export); err != nil {
+		log.Printf("Failed to write data export for user %s: %v", user.UserId, err)
+	}
+}
+
+// RequestEmailChange sends a confirmation link to the new address and a revert link to the old one
+func (u *UserHandler) RequestEmailChange(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.HandleError(ctx, http.StatusInternalServerError, "internal server error", "cannot cast into pkg.claims")
+		return
+	}
+
+	var body models.ChangeEmail
+	if err := ctx.ShouldBind(&body); err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	userCred, err := u.ur.GetPasswordFromID(ctx, user.UserId)
+	if err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	hashCfg := pkg.NewHashConfig()
+	isMatched, err := hashCfg.CompareHashAndPassword(body.Password, userCred.Password)
+	if err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+	if !isMatched {
+		utils.HandleError(ctx, http.StatusForbidden, "wrong password", "password mismatch on email change")
+		return
+	}
+
+	change, err := u.ur.RequestEmailChange(ctx, user.UserId, body.NewEmail)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrEmailTaken):
+			utils.HandleError(ctx, http.StatusConflict, err.Error(), "email change refused")
+		case errors.Is(err, repositories.ErrSameEmail):
+			utils.HandleError(ctx, http.StatusBadRequest, err.Error(), "email change refused")
+		default:
+			utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		}
+		return
+	}
+
+	confirmBody := fmt.Sprintf("Confirm your new email address by opening this link within %d hours:\n\n%s\n",
+		int(repositories.EmailConfirmWindow.Hours()), pkg.AppURL("/api/v1/auth/email/confirm?token="+change.ConfirmToken))
+	if err := u.mailer.Send(change.NewEmail, "Confirm your new email address", confirmBody); err != nil {
+		// Nobody can confirm the change, so it must not stay pending
+		if cancelErr := u.ur.CancelEmailChange(ctx, change.ConfirmToken); cancelErr != nil {
+			log.Printf("Failed to cancel email change of user %s after mail failure: %v", user.UserId, cancelErr)
+		}
+		utils.Error(ctx, http.StatusInternalServerError, "failed to send confirmation email", err)
+		return
+	}
+
+	noticeBody := fmt.Sprintf("Someone asked to change the email of your account to %s.\n\nIf this was not you, open this link within %d days to keep this address and sign out everywhere:\n\n%s\n",
+		change.NewEmail, int(repositories.EmailRevertWindow.Hours()/24), pkg.AppURL("/api/v1/auth/email/revert?token="+change.RevertToken))
+	if err := u.mailer.Send(change.OldEmail, "Your email address is being changed", noticeBody); err != nil {
+		log.Printf("Failed to send email change notice to old address: %v", err)
+	}
+
+	utils.Success(ctx, http.StatusAccepted, gin.H{"message": "confirmation link sent to the new address"})
+}
+
+// ShowEmailConfirmation is where the link in the confirmation email lands. It only checks
+// the token, so link previews and mail scanners cannot confirm the change on their own
+func (u *UserHandler) ShowEmailConfirmation(ctx *gin.Context) {
+	newEmail, err := u.ur.CheckEmailConfirmToken(ctx, ctx.Query("token"))
+	if err != nil {
+		if errors.Is(err, repositories.ErrEmailTokenInvalid) {
+			utils.HandleError(ctx, http.StatusBadRequest, err.Error(), "email confirmation link check failed")
+			return
+		}
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, gin.H{"email": newEmail, "message": "send the token with POST to confirm this address"})
+}
+
+func (u *UserHandler) ConfirmEmailChange(ctx *gin.Context) {
+	var body models.EmailToken
+	if err := ctx.ShouldBind(&body); err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "token is required", err.Error())
+		return
+	}
+
+	newEmail, err := u.ur.ConfirmEmailChange(ctx, body.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrEmailTokenInvalid):
+			utils.HandleError(ctx, http.StatusBadRequest, err.Error(), "email confirmation failed")
+		case errors.Is(err, repositories.ErrEmailTaken):
+			utils.HandleError(ctx, http.StatusConflict, err.Error(), "email confirmation failed")
+		default:
+			utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		}
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, gin.H{"email": newEmail})
+}
+
+// ShowEmailRevert is where the link in the notice to the old address lands, it only checks the token
+func (u *UserHandler) ShowEmailRevert(ctx *gin.Context) {
+	oldEmail, err := u.ur.CheckEmailRevertToken(ctx, ctx.Query("token"))
+	if err != nil {
+		if errors.Is(err, repositories.ErrEmailTokenInvalid) {
+			utils.HandleError(ctx, http.StatusBadRequest, err.Error(), "email revert link check failed")
+			return
+		}
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, gin.H{"email": oldEmail, "message": "send the token with POST to keep this address and sign out everywhere"})
+}
+
+// RevertEmailChange restores the old address and signs the user out, in case the change was not theirs
+func (u *UserHandler) RevertEmailChange(ctx *gin.Context) {
+	var body models.EmailToken
+	if err := ctx.ShouldBind(&body); err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "token is required", err.Error())
+		return
+	}
+
+	userID, err := u.ur.RevertEmailChange(ctx, body.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrEmailTokenInvalid):
+			utils.HandleError(ctx, http.StatusBadRequest, err.Error(), "email revert failed")
+		case errors.Is(err, repositories.ErrEmailTaken):
+			utils.HandleError(ctx, http.StatusConflict, err.Error(), "email revert failed")
+		default:
+			utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		}
+		return
+	}
+
+	if err := u.ac.BlacklistUserTokens(ctx.Request.Context(), userID, time.Now(), pkg.TokenLifetime); err != nil {
+		log.Printf("Failed to revoke tokens after email revert for user %s: %v", userID, err)
+	}
+
+	utils.Success(ctx, http.StatusOK, gin.H{"message": "email change reverted, all sessions signed out"})
+}
+
+// GetUserPosts lists the posts of a user (id or handle), optionally only with media or with their replies
+func (u *UserHandler) GetUserPosts(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.HandleError(ctx, http.StatusInternalServerError, "internal server error", "cannot cast into pkg.claims")
+		return
+	}
+
+	targetID, ok := u.resolveTarget(ctx)
+	if !ok {
+		return
+	}
+
+	var filter models.TimelineFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid query", err.Error())
+		return
+	}
+
+	cursorTime, cursorID, err := pkg.ParseCursor(ctx.Query("cursor"))
+	if err != nil {
+		utils.HandleError(ctx, http.StatusBadRequest, "invalid cursor", err.Error())
+		return
+	}
+
+	page, err := u.pr.GetUserPosts(ctx, user.UserId, targetID, filter, cursorTime, cursorID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrUserNotFound):
+			utils.HandleError(ctx, http.StatusNotFound, "user not found", err.Error())
+		case errors.Is(err, repositories.ErrPrivateAccount):
+			utils.HandleError(ctx, http.StatusForbidden, err.Error(), "timeline of private account")
+		default:
+			utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		}
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, page)
+}
+
+func (u *UserHandler) GetSuggestions(ctx *gin.Context) {
+	// Get the userID from token
+	claims, _ := ctx.Get("claims")
+	user, ok := claims.(pkg.Claims)
+	if !ok {
+		utils.HandleError(ctx, http.StatusInternalServerError, "internal server error", "cannot cast into pkg.claims")
+		return
+	}
+
+	suggestions, err := u.ur.GetSuggestions(ctx, user.UserId)
+	if err != nil {
+		utils.Error(ctx, http.StatusInternalServerError, "internal server error", err)
+		return
+	}
+
+	utils.Success(ctx, http.StatusOK, suggestions)
+}
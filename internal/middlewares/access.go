@@ -2,6 +2,7 @@ package middlewares
 
 import (
 	"net/http"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 	"github.com/radifan9/social-media-backend/internal/utils"
@@ -48,15 +49,15 @@ func Access(roles ...string) func(*gin.Context) {
 			utils.HandleMiddlewareError(ctx, http.StatusUnauthorized, "silahkan login kembali", "Unauthorized Access")
 			return
 		}
-		_, ok := claims.(pkg.Claims)
+		user, ok := claims.(pkg.Claims)
 		if !ok {
 			utils.HandleMiddlewareError(ctx, http.StatusInternalServerError, "Internal Server Error", "cannot cast into pkg.claims")
 			return
 		}
-		// if !slices.Contains(roles, user.Role) {
-		// 	utils.HandleMiddlewareError(ctx, http.StatusForbidden, "Anda tidak punya hak akses untuk resource ini", "Forbidden Access")
-		// 	return
-		// }
+		if !slices.Contains(roles, user.Role) {
+			utils.HandleMiddlewareError(ctx, http.StatusForbidden, "Anda tidak punya hak akses untuk resource ini", "Forbidden Access")
+			return
+		}
 		ctx.Next()
 	}
 }
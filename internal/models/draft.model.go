@@ -0,0 +1,70 @@
+package models
+
+import (
+	"mime/multipart"
+	"time"
+)
+
+// Draft states, a draft with a publish time is scheduled
+const (
+	DraftStatusDraft     = "draft"
+	DraftStatusScheduled = "scheduled"
+)
+
+type SaveDraft struct {
+	TextContent string                  `form:"text-content"`
+	Images      []*multipart.FileHeader `form:"images"`
+	AltTexts    []string                `form:"alt-texts"`
+	// public (default), followers, close_friends or mentioned
+	Visibility string `form:"visibility"`
+	// RFC 3339 time in the future, left empty the post stays a draft
+	PublishAt string `form:"publish_at" example:"2026-01-02T15:04:05Z"`
+}
+
+type EditDraft struct {
+	TextContent *string `form:"text-content"`
+	// Replaces all media of the draft when set
+	Images     []*multipart.FileHeader `form:"images"`
+	AltTexts   []string                `form:"alt-texts"`
+	Visibility *string                 `form:"visibility"`
+	// RFC 3339 time to (re)schedule, an empty value turns it back into a draft
+	PublishAt *string `form:"publish_at"`
+}
+
+// DraftChanges are the validated edits of a draft, nil fields stay as they are
+type DraftChanges struct {
+	TextContent *string
+	Visibility  *string
+	Media       []DraftMedia
+	// Media above replaces the current media
+	ReplaceMedia bool
+	PublishAt    *time.Time
+	// PublishAt above replaces the current publish time, nil makes it a draft
+	SetPublishAt bool
+}
+
+type DraftMedia struct {
+	MediaURL  string  `json:"media_url"`
+	MediaType string  `json:"media_type"`
+	SizeBytes int64   `json:"size_bytes"`
+	AltText   *string `json:"alt_text"`
+}
+
+type Draft struct {
+	ID          string       `json:"id"`
+	UserID      string       `json:"user_id"`
+	TextContent string       `json:"text_content"`
+	Visibility  string       `json:"visibility"`
+	Media       []DraftMedia `json:"media"`
+	Status      string       `json:"status"`
+	PublishAt   *time.Time   `json:"publish_at"`
+	// Why the scheduler could not publish it, the draft is unscheduled in that case
+	FailureReason *string   `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type DraftPage struct {
+	Drafts     []Draft `json:"drafts"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
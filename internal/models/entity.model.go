@@ -0,0 +1,16 @@
+package models
+
+const (
+	EntityHashtag = "hashtag"
+	EntityMention = "mention"
+)
+
+// Entity is a #hashtag or @mention inside a post or comment.
+// Start and End are code point offsets into the text, End is exclusive
+type Entity struct {
+	Type   string  `json:"type"`
+	Text   string  `json:"text"`
+	UserID *string `json:"user_id,omitempty"`
+	Start  int     `json:"start"`
+	End    int     `json:"end"`
+}
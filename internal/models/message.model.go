@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// Events pushed to the participants of a conversation
+const (
+	MessageEventMessage = "message"
+	MessageEventRead    = "read"
+)
+
+type CreateConversation struct {
+	// One user id starts a 1:1 conversation, more start a group
+	ParticipantIDs []string `json:"participant_ids" binding:"required"`
+	// Only used for groups
+	Name *string `json:"name"`
+}
+
+type SendMessage struct {
+	Body string `json:"body" binding:"required"`
+}
+
+type MarkRead struct {
+	// Marks everything up to this message as read, the latest message when empty
+	MessageID string `json:"message_id"`
+}
+
+type Message struct {
+	ID             string    `json:"id"`
+	ConversationID string    `json:"conversation_id"`
+	SenderID       string    `json:"sender_id"`
+	Body           string    `json:"body"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type ConversationParticipant struct {
+	UserSummary
+	// Read receipt, messages sent up to this time were read
+	LastReadAt *time.Time `json:"last_read_at"`
+}
+
+type Conversation struct {
+	ID            string                    `json:"id"`
+	IsGroup       bool                      `json:"is_group"`
+	Name          *string                   `json:"name"`
+	Participants  []ConversationParticipant `json:"participants"`
+	LastMessage   *Message                  `json:"last_message"`
+	UnreadCount   int                       `json:"unread_count"`
+	CreatedAt     time.Time                 `json:"created_at"`
+	LastMessageAt time.Time                 `json:"last_message_at"`
+}
+
+type ConversationPage struct {
+	Conversations []Conversation `json:"conversations"`
+	NextCursor    string         `json:"next_cursor,omitempty"`
+}
+
+type MessagePage struct {
+	Messages   []Message `json:"messages"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+type UnreadCount struct {
+	Conversations int `json:"conversations"`
+	Messages      int `json:"messages"`
+}
+
+// MessageEvent is pushed through Redis to the stream of every other participant
+type MessageEvent struct {
+	Type           string     `json:"type"`
+	ConversationID string     `json:"conversation_id"`
+	Message        *Message   `json:"message,omitempty"`
+	UserID         string     `json:"user_id,omitempty"`
+	ReadAt         *time.Time `json:"read_at,omitempty"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// NewPoll is a validated poll to attach to a new post
+type NewPoll struct {
+	Options  []string
+	ClosesAt time.Time
+}
+
+type PollVote struct {
+	OptionID string `json:"option_id" binding:"required"`
+}
+
+// Poll is embedded in a post. Votes and percentages are only set once the
+// viewer voted or the poll closed
+type Poll struct {
+	ClosesAt   time.Time    `json:"closes_at"`
+	Closed     bool         `json:"closed"`
+	TotalVotes *int         `json:"total_votes,omitempty"`
+	Options    []PollOption `json:"options"`
+	// Option the viewer voted for
+	ViewerVote *string `json:"viewer_vote"`
+}
+
+type PollOption struct {
+	ID         string   `json:"id"`
+	Position   int      `json:"position"`
+	Label      string   `json:"label"`
+	Votes      *int     `json:"votes,omitempty"`
+	Percentage *float64 `json:"percentage,omitempty"`
+}
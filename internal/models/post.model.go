@@ -5,38 +5,146 @@ import (
 	"time"
 )
 
+// Audiences a post can be shared with
+const (
+	VisibilityPublic       = "public"
+	VisibilityFollowers    = "followers"
+	VisibilityCloseFriends = "close_friends"
+	VisibilityMentioned    = "mentioned"
+)
+
 type CreatePost struct {
 	TextContent string                  `form:"text-content"`
 	Images      []*multipart.FileHeader `form:"images"`
+	AltTexts    []string                `form:"alt-texts"`
+	// public (default), followers, close_friends or mentioned
+	Visibility string `form:"visibility"`
+	// 2 to 4 options turn the post into a poll closing at PollClosesAt (RFC 3339)
+	PollOptions  []string `form:"poll-options"`
+	PollClosesAt string   `form:"poll-closes-at"`
+	// Set from the URL when quoting a post
+	QuotedPostID *string `form:"-"`
+	// Set from PollOptions and PollClosesAt once they are validated
+	Poll *NewPoll `form:"-"`
 }
 
 type Post struct {
-	ID          string    `json:"id"`
-	UserID      string    `json:"user_id"`
-	TextContent string    `json:"text_content"`
-	CreatedAt   time.Time `json:"created_at"`
-	Images      []string  `json:"images"`
+	ID          string      `json:"id"`
+	UserID      string      `json:"user_id"`
+	TextContent string      `json:"text_content"`
+	Visibility  string      `json:"visibility"`
+	CreatedAt   time.Time   `json:"created_at"`
+	Media       []PostImage `json:"media"`
+	Entities    []Entity    `json:"entities"`
+	// Held by the content filter until a moderator restores it
+	HeldForReview bool    `json:"held_for_review,omitempty"`
+	QuotedPostID  *string `json:"quoted_post_id,omitempty"`
+	Poll          *Poll   `json:"poll,omitempty"`
+}
+
+type Repost struct {
+	ID             string    `json:"id"`
+	UserID         string    `json:"user_id"`
+	RepostedPostID string    `json:"reposted_post_id"`
+	CreatedAt      time.Time `json:"created_at"`
 }
 
 type PostImage struct {
-	ID        string    `json:"id"`
-	PostID    string    `json:"post_id"`
-	ImageURL  string    `json:"image_url"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         string    `json:"id"`
+	PostID     string    `json:"post_id"`
+	MediaURL   string    `json:"media_url"`
+	MediaType  string    `json:"media_type"`
+	Status     string    `json:"status"`
+	PosterURL  *string   `json:"poster_url"`
+	SizeBytes  int64     `json:"size_bytes"`
+	DurationMs *int      `json:"duration_ms"`
+	Width      *int      `json:"width"`
+	Height     *int      `json:"height"`
+	Position   int       `json:"position"`
+	AltText    *string   `json:"alt_text"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// MediaJob is a gif or video waiting for the media worker
+type MediaJob struct {
+	ID        string
+	PostID    string
+	UserID    string
+	MediaURL  string
+	MediaType string
 }
 
 type FeedPost struct {
-	PostID      string        `json:"post_id"`
-	UserID      string        `json:"user_id"`
-	TextContent string        `json:"text_content"`
-	CreatedAt   time.Time     `json:"created_at"`
-	AuthorName  *string       `json:"author_name"`
-	LikeCount   int           `json:"like_count"`
-	Images      []string      `json:"images"`
-	Comments    []FeedComment `json:"comments"`
+	PostID         string        `json:"post_id"`
+	UserID         string        `json:"user_id"`
+	TextContent    string        `json:"text_content"`
+	Visibility     string        `json:"visibility"`
+	CreatedAt      time.Time     `json:"created_at"`
+	AuthorName     *string       `json:"author_name"`
+	AuthorUsername *string       `json:"author_username"`
+	AuthorAvatar   *string       `json:"author_avatar"`
+	LikeCount      int           `json:"like_count"`
+	RepostCount    int           `json:"repost_count"`
+	QuoteCount     int           `json:"quote_count"`
+	Bookmarked     bool          `json:"bookmarked"`
+	Media          []FeedMedia   `json:"media"`
+	Comments       []FeedComment `json:"comments"`
+	Entities       []Entity      `json:"entities"`
+	QuotedPost     *QuotedPost   `json:"quoted_post,omitempty"`
+	Poll           *Poll         `json:"poll,omitempty"`
+	// Set when the post is in a feed because someone the viewer follows reposted it
+	RepostedBy *Reposter `json:"reposted_by,omitempty"`
+
+	// State of the viewer, never part of the shared post cache
+	ViewerHasLiked      bool `json:"viewer_has_liked"`
+	ViewerHasCommented  bool `json:"viewer_has_commented"`
+	ViewerFollowsAuthor bool `json:"viewer_follows_author"`
+}
+
+// QuotedPost is the post embedded in a quote. Only PostID and Unavailable are set
+// when the quoted post was hidden or the viewer may not see it
+type QuotedPost struct {
+	PostID      string      `json:"post_id"`
+	UserID      string      `json:"user_id,omitempty"`
+	AuthorName  *string     `json:"author_name,omitempty"`
+	TextContent string      `json:"text_content,omitempty"`
+	CreatedAt   *time.Time  `json:"created_at,omitempty"`
+	Media       []FeedMedia `json:"media,omitempty"`
+	Unavailable bool        `json:"unavailable,omitempty"`
+}
+
+type Reposter struct {
+	UserID     string    `json:"user_id"`
+	Name       *string   `json:"name"`
+	RepostedAt time.Time `json:"reposted_at"`
+}
+
+// TimelineFilter narrows down the posts on a user's timeline
+type TimelineFilter struct {
+	MediaOnly bool `form:"media_only"`
+	// Also lists posts of other users the owner commented on
+	WithReplies bool `form:"with_replies"`
+}
+
+// PostPage is one page of a cursor-paginated post list
+type PostPage struct {
+	Posts      []FeedPost `json:"posts"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+type FeedMedia struct {
+	URL        string  `json:"url"`
+	MediaType  string  `json:"media_type"`
+	PosterURL  *string `json:"poster_url"`
+	DurationMs *int    `json:"duration_ms"`
+	Width      *int    `json:"width"`
+	Height     *int    `json:"height"`
+	Position   int     `json:"position"`
+	AltText    *string `json:"alt_text"`
 }
 
 type FeedComment struct {
+	UserID      string    `json:"user_id"`
 	Name        string    `json:"name"`
 	CommentText string    `json:"comment_text"`
 	CreatedAt   time.Time `json:"created_at"`
@@ -59,8 +167,23 @@ type CreateComment struct {
 }
 
 type CommentResponse struct {
+	ID        string    `json:"id"`
 	PostID    string    `json:"post_id"`
 	UserID    string    `json:"user_id"`
 	Comment   string    `json:"comment"`
+	Entities  []Entity  `json:"entities"`
 	CreatedAt time.Time `json:"created_at"`
+	// Held by the content filter until a moderator restores it
+	HeldForReview bool `json:"held_for_review,omitempty"`
+}
+
+type TrendingTopic struct {
+	Tag   string  `json:"tag"`
+	Score float64 `json:"score"`
+}
+
+type Trending struct {
+	Window string          `json:"window"`
+	Posts  []FeedPost      `json:"posts"`
+	Topics []TrendingTopic `json:"topics"`
 }
@@ -7,16 +7,36 @@ import (
 
 type UserProfile struct {
 	UserID    string    `json:"user_id"`
+	Username  string    `json:"username,omitempty"`
 	Email     string    `json:"email,omitempty"`
 	Name      string    `json:"name,omitempty"`
 	Bio       string    `json:"bio,omitempty"`
 	Avatar    string    `json:"avatar,omitempty"`
+	IsPrivate bool      `json:"is_private"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type EditUserProfile struct {
-	Name   string                `form:"name"`
-	Bio    string                `form:"bio"`
-	Avatar *multipart.FileHeader `form:"avatar"`
+	Username  string                `form:"username"`
+	Name      string                `form:"name"`
+	Bio       string                `form:"bio"`
+	Avatar    *multipart.FileHeader `form:"avatar"`
+	IsPrivate *bool                 `form:"is_private"`
+}
+
+// UserSummary is the short form of a profile used in lists
+type UserSummary struct {
+	UserID   string  `json:"user_id"`
+	Username *string `json:"username"`
+	Name     *string `json:"name"`
+	Bio      *string `json:"bio"`
+	Avatar   *string `json:"avatar"`
+}
+
+// FollowSuggestion is an account the user may want to follow, with why it was suggested
+type FollowSuggestion struct {
+	UserSummary
+	MutualCount int    `json:"mutual_count"`
+	Explanation string `json:"explanation"`
 }
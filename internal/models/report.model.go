@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Report targets
+const (
+	ReportPost    = "post"
+	ReportComment = "comment"
+	ReportUser    = "user"
+)
+
+// Report queue states
+const (
+	ReportOpen     = "open"
+	ReportClaimed  = "claimed"
+	ReportResolved = "resolved"
+)
+
+// Actions a moderator can take when resolving a report
+const (
+	ResolveDismiss = "dismiss"
+	ResolveHide    = "hide"
+	ResolveDelete  = "delete"
+	ResolveSuspend = "suspend"
+	// Unhides content that was hidden or held by the content filter
+	ResolveRestore = "restore"
+)
+
+// Reason of reports raised by the content filter instead of a user
+const ReasonContentFilter = "content_filter"
+
+type CreateReport struct {
+	TargetType string `json:"target_type" binding:"required,oneof=post comment user" example:"post"`
+	TargetID   string `json:"target_id" binding:"required,uuid" example:"2b0f5c1e-8a5b-4a53-9f3c-5d1f6f0b7e21"`
+	Reason     string `json:"reason" binding:"required,oneof=spam harassment hate_speech violence sexual_content self_harm misinformation impersonation other" example:"spam"`
+	Details    string `json:"details" binding:"max=1000"`
+}
+
+type ResolveReport struct {
+	Action string `json:"action" binding:"required,oneof=dismiss hide delete suspend restore" example:"hide"`
+	Note   string `json:"note" binding:"max=1000"`
+	// Only used by the suspend action
+	SuspendDays int `json:"suspend_days" binding:"omitempty,min=1,max=365" example:"7"`
+}
+
+type Report struct {
+	ID              string     `json:"id"`
+	ReporterID      *string    `json:"reporter_id"`
+	TargetType      string     `json:"target_type"`
+	TargetUserID    string     `json:"target_user_id"`
+	PostID          *string    `json:"post_id,omitempty"`
+	CommentID       *string    `json:"comment_id,omitempty"`
+	Reason          string     `json:"reason"`
+	Details         *string    `json:"details,omitempty"`
+	ContentSnapshot *string    `json:"content_snapshot,omitempty"`
+	Status          string     `json:"status"`
+	AssignedTo      *string    `json:"assigned_to,omitempty"`
+	Resolution      *string    `json:"resolution,omitempty"`
+	ResolutionNote  *string    `json:"resolution_note,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	ClaimedAt       *time.Time `json:"claimed_at,omitempty"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+}
+
+type ReportPage struct {
+	Reports    []Report `json:"reports"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
@@ -35,4 +35,6 @@ type ErrorResponse struct {
 	Success bool   `json:"success" example:"false"`
 	Status  int    `json:"status" example:"500"`
 	Error   string `json:"error" example:"error message"`
+	// Machine readable reason for errors clients need to tell apart
+	Code string `json:"code,omitempty" example:"account_suspended"`
 }
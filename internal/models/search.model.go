@@ -0,0 +1,18 @@
+package models
+
+const (
+	SearchPosts = "posts"
+	SearchUsers = "users"
+)
+
+type SearchQuery struct {
+	Query  string `form:"q" binding:"required"`
+	Type   string `form:"type"`
+	Cursor string `form:"cursor"`
+}
+
+type SearchPage struct {
+	Posts      []FeedPost    `json:"posts,omitempty"`
+	Users      []UserSummary `json:"users,omitempty"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
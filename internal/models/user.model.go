@@ -1,12 +1,86 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type User struct {
 	Id       string `db:"id" json:"id,omitempty"`
 	Email    string `db:"email" json:"email,omitempty"`
 	Password string `db:"password" json:"password,omitempty"`
+	Role     string `db:"role" json:"role,omitempty"`
+	Status   string `db:"status" json:"status,omitempty"`
+	// Set while Status is suspended
+	SuspendedUntil *time.Time `db:"suspended_until" json:"suspended_until,omitempty"`
+	// Set while the account waits to be purged
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
 }
 
+// User roles, moderators and admins can work the report queue
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 type RegisterUser struct {
+	Username string `json:"username" example:"john_doe"`
 	Email    string `json:"email" example:"user@example.com"`
 	Password string `json:"password" example:"User!23456789"`
 }
+
+// Account states, suspended accounts become active again once suspended_until passes
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+	StatusBanned    = "banned"
+)
+
+type SetAccountStatus struct {
+	Status string `json:"status" binding:"required,oneof=active suspended banned" example:"suspended"`
+	// Required when suspending
+	Until  *time.Time `json:"until" example:"2026-01-01T00:00:00Z"`
+	Reason string     `json:"reason" binding:"max=1000"`
+}
+
+type AccountStatus struct {
+	UserID         string     `json:"user_id"`
+	Status         string     `json:"status"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+}
+
+type DeleteAccount struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type AccountDeletion struct {
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+// DataExport is everything stored about a user, written to a ZIP by the export endpoint
+type DataExport struct {
+	// JSON documents keyed by file name
+	Sections map[string]json.RawMessage
+	// Uploaded files under public/
+	MediaFiles []string
+}
+
+type ChangeEmail struct {
+	NewEmail string `json:"new_email" binding:"required,email,max=255" example:"new@example.com"`
+	Password string `json:"password" binding:"required"`
+}
+
+// EmailToken is the token of a confirmation or revert link
+type EmailToken struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// EmailChange is a pending change together with the raw tokens for the two emails
+type EmailChange struct {
+	OldEmail     string
+	NewEmail     string
+	ConfirmToken string
+	RevertToken  string
+}
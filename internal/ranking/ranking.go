@@ -0,0 +1,65 @@
+// Package ranking scores posts for the "For You" feed. Scoring only depends on its inputs,
+// so the same candidates and snapshot time always give the same order
+package ranking
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Candidate is a post that may go into a viewer's "For You" feed, with the signals used to score it
+type Candidate struct {
+	PostID    string
+	AuthorID  string
+	CreatedAt time.Time
+	// Likes and comments within the velocity window
+	RecentLikes    int
+	RecentComments int
+	// Likes and comments the viewer gave the author recently
+	Interactions  int
+	FollowsAuthor bool
+	// Users the viewer follows who follow the author
+	MutualFollowers int
+}
+
+type Scored struct {
+	PostID string  `json:"post_id"`
+	Score  float64 `json:"score"`
+}
+
+// Score combines the signals of c as of now. Counts go through log1p so a single viral post
+// or a very chatty author cannot drown out everything else
+func Score(c Candidate, now time.Time, w Weights) float64 {
+	ageHours := math.Max(now.Sub(c.CreatedAt).Hours(), 0)
+	recency := math.Exp2(-ageHours / w.HalfLifeHours)
+
+	// Young posts are measured over their age, so they are not punished for a short window
+	window := math.Max(math.Min(ageHours, w.VelocityWindowHours), 1)
+	velocity := (float64(c.RecentLikes) + w.CommentWeight*float64(c.RecentComments)) / window
+
+	score := w.Recency*recency +
+		w.Velocity*math.Log1p(velocity) +
+		w.Affinity*math.Log1p(float64(c.Interactions)) +
+		w.SecondDegree*math.Log1p(float64(c.MutualFollowers))
+	if c.FollowsAuthor {
+		score += w.Following
+	}
+	return score
+}
+
+// Rank scores every candidate and orders them best first, ties broken by post id
+func Rank(candidates []Candidate, now time.Time, w Weights) []Scored {
+	scored := make([]Scored, len(candidates))
+	for i, c := range candidates {
+		scored[i] = Scored{PostID: c.PostID, Score: Score(c, now, w)}
+	}
+
+	sort.Slice(scored, func(i, j int) bool {
+		if scored[i].Score != scored[j].Score {
+			return scored[i].Score > scored[j].Score
+		}
+		return scored[i].PostID > scored[j].PostID
+	})
+	return scored
+}
@@ -0,0 +1,271 @@
+package ranking
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+var (
+	testNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	testWeights = Weights{
+		Recency:             4,
+		HalfLifeHours:       10,
+		Velocity:            2,
+		VelocityWindowHours: 5,
+		CommentWeight:       3,
+		Affinity:            1.5,
+		Following:           1,
+		SecondDegree:        0.5,
+	}
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name      string
+		candidate Candidate
+		want      float64
+	}{
+		{
+			name:      "new post without signals",
+			candidate: Candidate{CreatedAt: testNow},
+			want:      4,
+		},
+		{
+			name:      "recency halves every half life",
+			candidate: Candidate{CreatedAt: testNow.Add(-10 * time.Hour)},
+			want:      2,
+		},
+		{
+			name:      "recency after two half lives",
+			candidate: Candidate{CreatedAt: testNow.Add(-20 * time.Hour)},
+			want:      1,
+		},
+		{
+			name:      "posts from the future count as new",
+			candidate: Candidate{CreatedAt: testNow.Add(time.Hour)},
+			want:      4,
+		},
+		{
+			name:      "engagement of a young post is measured over its age",
+			candidate: Candidate{CreatedAt: testNow.Add(-2 * time.Hour), RecentLikes: 3, RecentComments: 1},
+			// (3 likes + 3 * 1 comment) / 2 hours
+			want: 4*math.Exp2(-0.2) + 2*math.Log1p(3),
+		},
+		{
+			name:      "engagement of an old post is measured over the window",
+			candidate: Candidate{CreatedAt: testNow.Add(-20 * time.Hour), RecentLikes: 10},
+			// 10 likes / 5 hours
+			want: 1 + 2*math.Log1p(2),
+		},
+		{
+			name:      "engagement of a brand new post is measured over at least an hour",
+			candidate: Candidate{CreatedAt: testNow, RecentLikes: 1},
+			want:      4 + 2*math.Log1p(1),
+		},
+		{
+			name:      "affinity with the author",
+			candidate: Candidate{CreatedAt: testNow.Add(-20 * time.Hour), Interactions: 7},
+			want:      1 + 1.5*math.Log1p(7),
+		},
+		{
+			name:      "following the author",
+			candidate: Candidate{CreatedAt: testNow.Add(-20 * time.Hour), FollowsAuthor: true},
+			want:      2,
+		},
+		{
+			name:      "followers in common",
+			candidate: Candidate{CreatedAt: testNow.Add(-20 * time.Hour), MutualFollowers: 3},
+			want:      1 + 0.5*math.Log1p(3),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Score(tt.candidate, testNow, testWeights)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Score() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRank(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []Candidate
+		want       []string
+	}{
+		{
+			name:       "no candidates",
+			candidates: nil,
+			want:       []string{},
+		},
+		{
+			name: "newer posts first",
+			candidates: []Candidate{
+				{PostID: "a", CreatedAt: testNow.Add(-30 * time.Hour)},
+				{PostID: "b", CreatedAt: testNow},
+				{PostID: "c", CreatedAt: testNow.Add(-10 * time.Hour)},
+			},
+			want: []string{"b", "c", "a"},
+		},
+		{
+			name: "engagement and affinity beat a small age difference",
+			candidates: []Candidate{
+				{PostID: "a", CreatedAt: testNow},
+				{PostID: "b", CreatedAt: testNow.Add(-time.Hour), RecentLikes: 20},
+				{PostID: "c", CreatedAt: testNow.Add(-time.Hour), Interactions: 10, FollowsAuthor: true},
+			},
+			want: []string{"b", "c", "a"},
+		},
+		{
+			name: "ties are broken by post id, highest first",
+			candidates: []Candidate{
+				{PostID: "b", CreatedAt: testNow},
+				{PostID: "c", CreatedAt: testNow},
+				{PostID: "a", CreatedAt: testNow},
+			},
+			want: []string{"c", "b", "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0, len(tt.candidates))
+			for _, s := range Rank(tt.candidates, testNow, testWeights) {
+				got = append(got, s.PostID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Rank() order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankIsStableAcrossInputOrder(t *testing.T) {
+	candidates := []Candidate{
+		{PostID: "a", CreatedAt: testNow.Add(-3 * time.Hour), RecentLikes: 4},
+		{PostID: "b", CreatedAt: testNow.Add(-3 * time.Hour), RecentLikes: 4},
+		{PostID: "c", CreatedAt: testNow.Add(-8 * time.Hour), Interactions: 2},
+	}
+	reversed := slices.Clone(candidates)
+	slices.Reverse(reversed)
+
+	first, second := Rank(candidates, testNow, testWeights), Rank(reversed, testNow, testWeights)
+	if !slices.Equal(first, second) {
+		t.Errorf("Rank() depends on input order: %v vs %v", first, second)
+	}
+}
+
+func TestLoadWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Weights
+		wantErr bool
+	}{
+		{
+			name:    "fields left out keep their default",
+			content: `{"recency": 5, "half_life_hours": 24}`,
+			want: func() Weights {
+				w := DefaultWeights()
+				w.Recency = 5
+				w.HalfLifeHours = 24
+				return w
+			}(),
+		},
+		{
+			name:    "malformed json",
+			content: `{"recency": `,
+			wantErr: true,
+		},
+		{
+			name:    "wrong type",
+			content: `{"recency": "high"}`,
+			wantErr: true,
+		},
+		{
+			name:    "zero half life",
+			content: `{"half_life_hours": 0}`,
+			wantErr: true,
+		},
+		{
+			name:    "negative velocity window",
+			content: `{"velocity_window_hours": -1}`,
+			wantErr: true,
+		},
+		{
+			name:    "negative weight",
+			content: `{"affinity": -0.5}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "for-you.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := LoadWeights(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("LoadWeights() accepted %s", tt.content)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadWeights() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("LoadWeights() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadWeightsWithoutFile(t *testing.T) {
+	got, err := LoadWeights("")
+	if err != nil {
+		t.Fatalf("LoadWeights(\"\") error = %v", err)
+	}
+	if got != DefaultWeights() {
+		t.Errorf("LoadWeights(\"\") = %+v, want the defaults", got)
+	}
+
+	if _, err := LoadWeights(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadWeights() accepted a missing file")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(w *Weights)
+		wantErr bool
+	}{
+		{name: "test weights", change: func(w *Weights) {}},
+		{name: "zero weights are allowed", change: func(w *Weights) { w.Recency, w.Following = 0, 0 }},
+		{name: "zero half life", change: func(w *Weights) { w.HalfLifeHours = 0 }, wantErr: true},
+		{name: "zero velocity window", change: func(w *Weights) { w.VelocityWindowHours = 0 }, wantErr: true},
+		{name: "negative recency", change: func(w *Weights) { w.Recency = -1 }, wantErr: true},
+		{name: "negative comment weight", change: func(w *Weights) { w.CommentWeight = -1 }, wantErr: true},
+		{name: "negative second degree", change: func(w *Weights) { w.SecondDegree = -1 }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := testWeights
+			tt.change(&w)
+			if err := w.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package ranking
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Weights tunes the "For You" scoring, read from the JSON file pointed to by FOR_YOU_CONFIG.
+// Fields left out of the file keep their default
+type Weights struct {
+	Recency float64 `json:"recency"`
+	// A post loses half of its recency score every HalfLifeHours
+	HalfLifeHours float64 `json:"half_life_hours"`
+	Velocity      float64 `json:"velocity"`
+	// Likes and comments newer than this count towards velocity
+	VelocityWindowHours float64 `json:"velocity_window_hours"`
+	// How many likes one comment is worth
+	CommentWeight float64 `json:"comment_weight"`
+	Affinity      float64 `json:"affinity"`
+	Following     float64 `json:"following"`
+	SecondDegree  float64 `json:"second_degree"`
+}
+
+func DefaultWeights() Weights {
+	return Weights{
+		Recency:             3,
+		HalfLifeHours:       12,
+		Velocity:            1.5,
+		VelocityWindowHours: 6,
+		CommentWeight:       2,
+		Affinity:            1,
+		Following:           1,
+		SecondDegree:        0.5,
+	}
+}
+
+// LoadWeights reads the weights from path. An empty path gives the defaults
+func LoadWeights(path string) (Weights, error) {
+	w := DefaultWeights()
+	if path == "" {
+		return w, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Weights{}, err
+	}
+	if err := json.Unmarshal(raw, &w); err != nil {
+		return Weights{}, fmt.Errorf("invalid for you config: %w", err)
+	}
+
+	return w, w.validate()
+}
+
+func (w Weights) validate() error {
+	if w.HalfLifeHours <= 0 || w.VelocityWindowHours <= 0 {
+		return errors.New("half_life_hours and velocity_window_hours must be positive")
+	}
+	for _, v := range []float64{w.Recency, w.Velocity, w.CommentWeight, w.Affinity, w.Following, w.SecondDegree} {
+		if v < 0 {
+			return errors.New("weights cannot be negative")
+		}
+	}
+	return nil
+}
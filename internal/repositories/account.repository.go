@@ -0,0 +1,212 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/radifan9/social-media-backend/pkg"
+)
+
+// AccountDeletionGrace is how long a deleted account can still be restored by logging in
+const AccountDeletionGrace = 30 * 24 * time.Hour
+
+var (
+	ErrSuspendUntil   = errors.New("until must be a time in the future when suspending")
+	ErrAccountDeleted = errors.New("account has been deleted")
+)
+
+// SetAccountStatus activates, suspends or bans a user and revokes their current tokens.
+// The change is recorded in the moderation audit log
+func (m *ModerationRepository) SetAccountStatus(ctx context.Context, adminID, userID string, body models.SetAccountStatus) (models.AccountStatus, error) {
+	if adminID == userID {
+		return models.AccountStatus{}, ErrSelfAction
+	}
+
+	var until *time.Time
+	if body.Status == models.StatusSuspended {
+		if body.Until == nil || !body.Until.After(time.Now()) {
+			return models.AccountStatus{}, ErrSuspendUntil
+		}
+		until = body.Until
+	}
+
+	// Begin transaction
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return models.AccountStatus{}, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Println("failed to rollback transaction:", rollbackErr)
+			}
+		}
+	}()
+
+	query := `
+		UPDATE users
+		SET status = $2, suspended_until = $3
+		WHERE id = $1
+		RETURNING id, status, suspended_until
+	`
+	var status models.AccountStatus
+	if err = tx.QueryRow(ctx, query, userID, body.Status, until).Scan(&status.UserID, &status.Status, &status.SuspendedUntil); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrUserNotFound
+		}
+		return models.AccountStatus{}, err
+	}
+
+	details := map[string]any{"status": body.Status}
+	if until != nil {
+		details["suspended_until"] = *until
+	}
+	if body.Reason != "" {
+		details["reason"] = body.Reason
+	}
+	if err = appendAuditLog(ctx, tx, adminID, "set_status", nil, models.ReportUser, &userID, details); err != nil {
+		return models.AccountStatus{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return models.AccountStatus{}, err
+	}
+
+	// Tokens issued before now stop working, login checks the status from here on
+	if err := m.ac.BlacklistUserTokens(ctx, userID, time.Now(), pkg.TokenLifetime); err != nil {
+		log.Printf("Failed to revoke tokens of user %s: %v", userID, err)
+	}
+
+	return status, nil
+}
+
+// DeleteAccount soft deletes userID. Their content is hidden right away
+// and the account is purged once AccountDeletionGrace has passed
+func (u *UserRepository) DeleteAccount(ctx context.Context, userID string) (models.AccountDeletion, error) {
+	query := `
+		UPDATE users
+		SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING deleted_at
+	`
+
+	var deletion models.AccountDeletion
+	if err := u.db.QueryRow(ctx, query, userID).Scan(&deletion.DeletedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.AccountDeletion{}, ErrAccountDeleted
+		}
+		return models.AccountDeletion{}, err
+	}
+	deletion.PurgeAt = deletion.DeletedAt.Add(AccountDeletionGrace)
+
+	// Followers should stop seeing the posts
+	NewPostRepository(u.db, u.rdb).InvalidateFollowersFeedCache(ctx, userID)
+
+	return deletion, nil
+}
+
+// RestoreAccount cancels a pending deletion while still in the grace period
+func (u *UserRepository) RestoreAccount(ctx context.Context, userID string) error {
+	query := `
+		UPDATE users
+		SET deleted_at = NULL
+		WHERE id = $1 AND deleted_at > $2
+	`
+
+	tag, err := u.db.Exec(ctx, query, userID, time.Now().Add(-AccountDeletionGrace))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAccountDeleted
+	}
+
+	NewPostRepository(u.db, u.rdb).InvalidateFollowersFeedCache(ctx, userID)
+	return nil
+}
+
+// PurgeDeletedAccount hard deletes one account whose grace period is over and returns
+// its id and the uploaded files it owned. Rows go with the ON DELETE CASCADE foreign keys,
+// the caller removes the files. userID is empty when nothing is due
+func (u *UserRepository) PurgeDeletedAccount(ctx context.Context) (userID string, files []string, err error) {
+	// Begin transaction
+	tx, err := u.db.Begin(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Println("failed to rollback transaction:", rollbackErr)
+			}
+		}
+	}()
+
+	// SKIP LOCKED keeps replicas from purging the same account
+	claimQuery := `
+		SELECT id FROM users
+		WHERE deleted_at < $1
+		ORDER BY deleted_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`
+	if err = tx.QueryRow(ctx, claimQuery, time.Now().Add(-AccountDeletionGrace)).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = tx.Commit(ctx)
+			return "", nil, err
+		}
+		return "", nil, err
+	}
+
+	filesQuery := `
+		SELECT 'public/post_images', pi.media_url
+		FROM post_images pi
+		INNER JOIN posts p ON pi.post_id = p.id
+		WHERE p.user_id = $1
+		UNION ALL
+		SELECT 'public/post_images', pi.poster_url
+		FROM post_images pi
+		INNER JOIN posts p ON pi.post_id = p.id
+		WHERE p.user_id = $1 AND pi.poster_url IS NOT NULL
+		UNION ALL
+		SELECT 'public/post_images', dm->>'media_url'
+		FROM post_drafts d, jsonb_array_elements(d.media) dm
+		WHERE d.user_id = $1
+		UNION ALL
+		SELECT 'public/avatars', avatar
+		FROM user_profiles
+		WHERE user_id = $1 AND avatar IS NOT NULL AND avatar <> ''
+	`
+	rows, err := tx.Query(ctx, filesQuery, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	for rows.Next() {
+		var dir, name string
+		if err = rows.Scan(&dir, &name); err != nil {
+			rows.Close()
+			return "", nil, err
+		}
+		// Older rows store the full "public/..." path, newer ones only the file name
+		files = append(files, filepath.Join(dir, filepath.Base(name)))
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return "", nil, err
+	}
+
+	if _, err = tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID); err != nil {
+		return "", nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return "", nil, err
+	}
+
+	return userID, files, nil
+}
@@ -57,6 +57,21 @@ func (a *AuthCacheManager) IsTokenBlacklisted(ctx context.Context, tokenString s
 	return exists
 }
 
+// BlacklistUserTokens invalidates every token of a user issued before the given time.
+// ttl should cover the token lifetime, older tokens expire on their own
+func (a *AuthCacheManager) BlacklistUserTokens(ctx context.Context, userID string, at time.Time, ttl time.Duration) error {
+	key := fmt.Sprintf("sosmed:user_blacklist:%s", userID)
+
+	// key : sosmed:user_blacklist:<userID>
+	// value : unix timestamp, tokens issued before it are rejected
+	if err := a.rdb.Set(ctx, key, at.Unix(), ttl).Err(); err != nil {
+		log.Printf("Failed to blacklist user tokens: %v", err)
+		return fmt.Errorf("failed to blacklist user tokens: %w", err)
+	}
+
+	return nil
+}
+
 // IsUserTokensBlacklisted checks if all tokens for a user should be considered invalid
 func (a *AuthCacheManager) IsUserTokensBlacklisted(ctx context.Context, userID string, tokenIssuedAt time.Time) bool {
 	key := fmt.Sprintf("sosmed:user_blacklist:%s", userID)
@@ -0,0 +1,165 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/radifan9/social-media-backend/internal/models"
+)
+
+var ErrSelfAction = errors.New("cannot do this to yourself")
+
+// BlockUser blocks targetID and removes any follow, follow request or close friend entry between the two users
+func (u *UserRepository) BlockUser(ctx context.Context, userID, targetID string) error {
+	if userID == targetID {
+		return ErrSelfAction
+	}
+
+	// Begin transaction
+	tx, err := u.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Println("failed to rollback transaction:", rollbackErr)
+			}
+		}
+	}()
+
+	blockQuery := `
+		INSERT INTO user_blocks (blocker_id, blocked_id)
+		VALUES ($1, $2)
+		ON CONFLICT (blocker_id, blocked_id) DO NOTHING
+	`
+	if _, err = tx.Exec(ctx, blockQuery, userID, targetID); err != nil {
+		return err
+	}
+
+	// Pending requests go first, an approval racing with the block then
+	// commits before the unfollow below reads user_followers
+	requestsQuery := `
+		DELETE FROM follow_requests
+		WHERE (requester_id = $1 AND target_id = $2)
+			OR (requester_id = $2 AND target_id = $1)
+	`
+	if _, err = tx.Exec(ctx, requestsQuery, userID, targetID); err != nil {
+		return err
+	}
+
+	unfollowQuery := `
+		DELETE FROM user_followers
+		WHERE (user_id = $1 AND follower_id = $2)
+			OR (user_id = $2 AND follower_id = $1)
+	`
+	if _, err = tx.Exec(ctx, unfollowQuery, userID, targetID); err != nil {
+		return err
+	}
+
+	closeFriendsQuery := `
+		DELETE FROM close_friends
+		WHERE (user_id = $1 AND friend_id = $2)
+			OR (user_id = $2 AND friend_id = $1)
+	`
+	if _, err = tx.Exec(ctx, closeFriendsQuery, userID, targetID); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	// Both feeds may contain the other user's posts
+	keys := []string{feedCacheKey(userID), feedCacheKey(targetID), forYouCacheKey(userID), forYouCacheKey(targetID)}
+	if err := u.rdb.Del(ctx, keys...).Err(); err != nil {
+		log.Printf("Failed to invalidate feed cache after block: %v", err)
+	}
+
+	return nil
+}
+
+func (u *UserRepository) UnblockUser(ctx context.Context, userID, targetID string) error {
+	query := `DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2`
+	_, err := u.db.Exec(ctx, query, userID, targetID)
+	return err
+}
+
+// MuteUser hides targetID from the feed and notifications of userID, without them knowing
+func (u *UserRepository) MuteUser(ctx context.Context, userID, targetID string) error {
+	if userID == targetID {
+		return ErrSelfAction
+	}
+
+	query := `
+		INSERT INTO user_mutes (muter_id, muted_id)
+		VALUES ($1, $2)
+		ON CONFLICT (muter_id, muted_id) DO NOTHING
+	`
+	if _, err := u.db.Exec(ctx, query, userID, targetID); err != nil {
+		return err
+	}
+
+	if err := u.rdb.Del(ctx, feedCacheKey(userID), forYouCacheKey(userID)).Err(); err != nil {
+		log.Printf("Failed to invalidate feed cache after mute: %v", err)
+	}
+
+	return nil
+}
+
+func (u *UserRepository) UnmuteUser(ctx context.Context, userID, targetID string) error {
+	query := `DELETE FROM user_mutes WHERE muter_id = $1 AND muted_id = $2`
+	if _, err := u.db.Exec(ctx, query, userID, targetID); err != nil {
+		return err
+	}
+
+	if err := u.rdb.Del(ctx, feedCacheKey(userID)).Err(); err != nil {
+		log.Printf("Failed to invalidate feed cache after unmute: %v", err)
+	}
+
+	return nil
+}
+
+// GetBlockedUsers lists the accounts userID has blocked, newest first
+func (u *UserRepository) GetBlockedUsers(ctx context.Context, userID string) ([]models.UserSummary, error) {
+	query := `
+		SELECT up.user_id, up.username, up.name, up.bio, up.avatar
+		FROM user_blocks ub
+		INNER JOIN user_profiles up ON ub.blocked_id = up.user_id
+		WHERE ub.blocker_id = $1
+		ORDER BY ub.created_at DESC
+	`
+	return u.queryUserSummaries(ctx, query, userID)
+}
+
+// GetMutedUsers lists the accounts userID has muted, newest first
+func (u *UserRepository) GetMutedUsers(ctx context.Context, userID string) ([]models.UserSummary, error) {
+	query := `
+		SELECT up.user_id, up.username, up.name, up.bio, up.avatar
+		FROM user_mutes um
+		INNER JOIN user_profiles up ON um.muted_id = up.user_id
+		WHERE um.muter_id = $1
+		ORDER BY um.created_at DESC
+	`
+	return u.queryUserSummaries(ctx, query, userID)
+}
+
+func (u *UserRepository) queryUserSummaries(ctx context.Context, query string, args ...any) ([]models.UserSummary, error) {
+	rows, err := u.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.UserSummary{}
+	for rows.Next() {
+		var user models.UserSummary
+		if err := rows.Scan(&user.UserID, &user.Username, &user.Name, &user.Bio, &user.Avatar); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
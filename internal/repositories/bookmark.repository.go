@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/radifan9/social-media-backend/internal/models"
+)
+
+// BookmarkPost saves postID for userID. Saving a post twice is a no-op
+func (p *PostRepository) BookmarkPost(ctx context.Context, userID, postID string) error {
+	visible, err := p.canInteract(ctx, userID, postID)
+	if err != nil {
+		return err
+	}
+	if !visible {
+		return ErrPostNotFound
+	}
+
+	query := `
+		INSERT INTO bookmarks (user_id, post_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, post_id) DO NOTHING
+	`
+	_, err = p.db.Exec(ctx, query, userID, postID)
+	return err
+}
+
+// RemoveBookmark unsaves postID for userID. Removing a missing bookmark is a no-op
+func (p *PostRepository) RemoveBookmark(ctx context.Context, userID, postID string) error {
+	query := `DELETE FROM bookmarks WHERE user_id = $1 AND post_id = $2`
+	_, err := p.db.Exec(ctx, query, userID, postID)
+	return err
+}
+
+// GetBookmarks returns the posts userID saved, most recently saved first.
+// Posts the user cannot see anymore are left out
+func (p *PostRepository) GetBookmarks(ctx context.Context, userID string, cursorTime *time.Time, cursorID *string) (models.PostPage, error) {
+	query := `
+		SELECT b.post_id, b.created_at
+		FROM bookmarks b
+		INNER JOIN posts p ON b.post_id = p.id
+		WHERE b.user_id = $1
+			AND p.hidden_at IS NULL
+			AND ($2::timestamptz IS NULL OR (b.created_at, b.post_id) < ($2, $3::uuid))
+			AND ` + canViewAuthorSQL("$1", "p.user_id") + `
+			AND ` + postAudienceSQL("$1", "p") + `
+		ORDER BY b.created_at DESC, b.post_id DESC
+		LIMIT $4
+	`
+
+	return p.queryPostPage(ctx, userID, query, userID, cursorTime, cursorID, pageSize+1)
+}
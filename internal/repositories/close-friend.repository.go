@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"log"
+
+	"github.com/radifan9/social-media-backend/internal/models"
+)
+
+// AddCloseFriend puts friendID on the close friends list of userID, the audience of
+// their close_friends posts. Adding someone twice is a no-op
+func (u *UserRepository) AddCloseFriend(ctx context.Context, userID, friendID string) error {
+	if userID == friendID {
+		return ErrSelfAction
+	}
+
+	// Users in a block with userID are reported as not found
+	query := `
+		INSERT INTO close_friends (user_id, friend_id)
+		SELECT $1::uuid, u.id
+		FROM users u
+		WHERE u.id = $2
+			AND ` + notBlockedSQL("$1::uuid", "u.id") + `
+			AND ` + notDeletedSQL("u.id") + `
+		ON CONFLICT (user_id, friend_id) DO NOTHING
+	`
+	tag, err := u.db.Exec(ctx, query, userID, friendID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		var listed bool
+		if err := u.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM close_friends WHERE user_id = $1 AND friend_id = $2)`, userID, friendID).Scan(&listed); err != nil {
+			return err
+		}
+		if !listed {
+			return ErrUserNotFound
+		}
+		return nil
+	}
+
+	u.invalidateCloseFriendFeeds(ctx, friendID)
+	return nil
+}
+
+// RemoveCloseFriend takes friendID off the close friends list of userID.
+// Removing someone who is not on the list is a no-op
+func (u *UserRepository) RemoveCloseFriend(ctx context.Context, userID, friendID string) error {
+	query := `DELETE FROM close_friends WHERE user_id = $1 AND friend_id = $2`
+	tag, err := u.db.Exec(ctx, query, userID, friendID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() > 0 {
+		u.invalidateCloseFriendFeeds(ctx, friendID)
+	}
+	return nil
+}
+
+// GetCloseFriends lists the close friends of userID, most recently added first
+func (u *UserRepository) GetCloseFriends(ctx context.Context, userID string) ([]models.UserSummary, error) {
+	query := `
+		SELECT up.user_id, up.username, up.name, up.bio, up.avatar
+		FROM close_friends cf
+		INNER JOIN user_profiles up ON cf.friend_id = up.user_id
+		WHERE cf.user_id = $1 AND ` + notDeletedSQL("cf.friend_id") + `
+		ORDER BY cf.created_at DESC
+	`
+	return u.queryUserSummaries(ctx, query, userID)
+}
+
+// invalidateCloseFriendFeeds drops the feeds of friendID, which may gain or lose close_friends posts
+func (u *UserRepository) invalidateCloseFriendFeeds(ctx context.Context, friendID string) {
+	if err := u.rdb.Del(ctx, feedCacheKey(friendID), forYouCacheKey(friendID)).Err(); err != nil {
+		log.Printf("Failed to invalidate feed cache after close friends change: %v", err)
+	}
+}
@@ -0,0 +1,305 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/radifan9/social-media-backend/internal/filters"
+	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/radifan9/social-media-backend/pkg"
+)
+
+var (
+	ErrDraftNotFound = errors.New("draft not found")
+	ErrEmptyDraft    = errors.New("draft must have text or media")
+)
+
+// Failure reason of a scheduled post that could not be stored, the cause is only logged
+const draftPublishFailed = "the post could not be published, schedule it again"
+
+const draftColumns = `
+	id, user_id, text_content, visibility, media, publish_at, failure_reason, created_at, updated_at
+`
+
+func scanDraft(row pgx.Row) (models.Draft, error) {
+	var d models.Draft
+	if err := row.Scan(
+		&d.ID, &d.UserID, &d.TextContent, &d.Visibility, &d.Media, &d.PublishAt, &d.FailureReason, &d.CreatedAt, &d.UpdatedAt,
+	); err != nil {
+		return models.Draft{}, err
+	}
+
+	d.Status = models.DraftStatusDraft
+	if d.PublishAt != nil {
+		d.Status = models.DraftStatusScheduled
+	}
+	if d.Media == nil {
+		d.Media = []models.DraftMedia{}
+	}
+	return d, nil
+}
+
+// CreateDraft stores a draft, or a scheduled post when publishAt is set
+func (p *PostRepository) CreateDraft(ctx context.Context, userID, text, visibility string, media []models.DraftMedia, publishAt *time.Time) (models.Draft, error) {
+	if media == nil {
+		media = []models.DraftMedia{}
+	}
+
+	query := `
+		INSERT INTO post_drafts (user_id, text_content, visibility, media, publish_at)
+		VALUES ($1, $2, $3, $4::jsonb, $5)
+		RETURNING ` + draftColumns
+
+	return scanDraft(p.db.QueryRow(ctx, query, userID, text, visibility, media, publishAt))
+}
+
+// GetDrafts returns the drafts and scheduled posts of userID, newest first
+func (p *PostRepository) GetDrafts(ctx context.Context, userID string, cursorTime *time.Time, cursorID *string) (models.DraftPage, error) {
+	query := `
+		SELECT ` + draftColumns + `
+		FROM post_drafts
+		WHERE user_id = $1
+			AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3::uuid))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4
+	`
+
+	rows, err := p.db.Query(ctx, query, userID, cursorTime, cursorID, pageSize+1)
+	if err != nil {
+		return models.DraftPage{}, err
+	}
+	defer rows.Close()
+
+	page := models.DraftPage{Drafts: []models.Draft{}}
+	for rows.Next() {
+		d, err := scanDraft(rows)
+		if err != nil {
+			return models.DraftPage{}, err
+		}
+		page.Drafts = append(page.Drafts, d)
+	}
+	if err := rows.Err(); err != nil {
+		return models.DraftPage{}, err
+	}
+
+	// The extra row only tells there is a next page
+	if len(page.Drafts) > pageSize {
+		page.Drafts = page.Drafts[:pageSize]
+		last := page.Drafts[pageSize-1]
+		page.NextCursor = pkg.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	return page, nil
+}
+
+// EditDraft applies changes to a draft of userID. Editing a draft clears the failure reason
+// of an earlier publish attempt. When the media is replaced, the files of the old media
+// are returned so the caller can remove them
+func (p *PostRepository) EditDraft(ctx context.Context, userID, draftID string, changes models.DraftChanges) (draft models.Draft, oldFiles []string, err error) {
+	// Begin transaction
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return models.Draft{}, nil, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Println("failed to rollback transaction:", rollbackErr)
+			}
+		}
+	}()
+
+	// Lock the draft so the scheduler cannot publish it halfway through the edit
+	lockQuery := `SELECT ` + draftColumns + ` FROM post_drafts WHERE id = $1 AND user_id = $2 FOR UPDATE`
+	draft, err = scanDraft(tx.QueryRow(ctx, lockQuery, draftID, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrDraftNotFound
+		}
+		return models.Draft{}, nil, err
+	}
+
+	if changes.TextContent != nil {
+		draft.TextContent = *changes.TextContent
+	}
+	if changes.Visibility != nil {
+		draft.Visibility = *changes.Visibility
+	}
+	if changes.ReplaceMedia {
+		for _, m := range draft.Media {
+			oldFiles = append(oldFiles, filepath.Join("public/post_images", filepath.Base(m.MediaURL)))
+		}
+		draft.Media = changes.Media
+		if draft.Media == nil {
+			draft.Media = []models.DraftMedia{}
+		}
+	}
+	if changes.SetPublishAt {
+		draft.PublishAt = changes.PublishAt
+	}
+
+	if strings.TrimSpace(draft.TextContent) == "" && len(draft.Media) == 0 {
+		err = ErrEmptyDraft
+		return models.Draft{}, nil, err
+	}
+
+	updateQuery := `
+		UPDATE post_drafts
+		SET text_content = $2, visibility = $3, media = $4::jsonb, publish_at = $5, failure_reason = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING ` + draftColumns
+	draft, err = scanDraft(tx.QueryRow(ctx, updateQuery, draftID, draft.TextContent, draft.Visibility, draft.Media, draft.PublishAt))
+	if err != nil {
+		return models.Draft{}, nil, err
+	}
+
+	// Commit
+	if err = tx.Commit(ctx); err != nil {
+		return models.Draft{}, nil, err
+	}
+
+	return draft, oldFiles, nil
+}
+
+// DeleteDraft removes a draft of userID, cancelling it if it was scheduled.
+// It returns the media files of the draft so the caller can remove them
+func (p *PostRepository) DeleteDraft(ctx context.Context, userID, draftID string) ([]string, error) {
+	query := `DELETE FROM post_drafts WHERE id = $1 AND user_id = $2 RETURNING media`
+
+	var media []models.DraftMedia
+	if err := p.db.QueryRow(ctx, query, draftID, userID).Scan(&media); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDraftNotFound
+		}
+		return nil, err
+	}
+
+	files := make([]string, 0, len(media))
+	for _, m := range media {
+		files = append(files, filepath.Join("public/post_images", filepath.Base(m.MediaURL)))
+	}
+	return files, nil
+}
+
+// PublishDueDraft publishes the scheduled post that is due the longest, running the
+// content filter on its text again since the rules may have changed since it was saved.
+// A rejected post, or one that fails to be stored, goes back to being a draft with the reason,
+// so it cannot hold up the posts due after it.
+// It returns an empty draftID when nothing is due and an empty postID when the post was not published
+func (p *PostRepository) PublishDueDraft(ctx context.Context, filter filters.ContentFilter) (draftID, postID string, err error) {
+	// Begin transaction
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Println("failed to rollback transaction:", rollbackErr)
+			}
+		}
+	}()
+
+	// SKIP LOCKED keeps replicas from publishing the same post twice. Posts of banned or
+	// suspended accounts wait until the account is active again, those of deleted ones for the purge
+	claimQuery := `
+		SELECT ` + draftColumns + `
+		FROM post_drafts d
+		WHERE d.publish_at <= CURRENT_TIMESTAMP
+			AND ` + accountActiveSQL("d.user_id") + `
+		ORDER BY d.publish_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`
+	draft, err := scanDraft(tx.QueryRow(ctx, claimQuery))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = tx.Commit(ctx)
+			return "", "", err
+		}
+		return "", "", err
+	}
+
+	var holdReason *string
+	verdict := filter.Check(ctx, draft.TextContent)
+	switch verdict.Action {
+	case filters.Reject:
+		if err = unscheduleDraft(ctx, tx, draft.ID, verdict.Reason); err != nil {
+			return "", "", err
+		}
+		return draft.ID, "", nil
+	case filters.Hold:
+		holdReason = &verdict.Reason
+	}
+
+	media := make([]models.PostImage, 0, len(draft.Media))
+	for _, m := range draft.Media {
+		media = append(media, models.PostImage{
+			MediaURL:  m.MediaURL,
+			MediaType: m.MediaType,
+			SizeBytes: m.SizeBytes,
+			AltText:   m.AltText,
+		})
+	}
+
+	// The savepoint keeps the claim when storing the post fails
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	post, publishErr := p.publishDraft(ctx, sp, draft, media, holdReason)
+	if publishErr != nil {
+		log.Printf("Failed to publish scheduled post %s: %v", draft.ID, publishErr)
+		if err = sp.Rollback(ctx); err != nil {
+			return "", "", err
+		}
+		if err = unscheduleDraft(ctx, tx, draft.ID, draftPublishFailed); err != nil {
+			return "", "", err
+		}
+		return draft.ID, "", nil
+	}
+
+	// Commit
+	if err = tx.Commit(ctx); err != nil {
+		return "", "", err
+	}
+
+	p.afterPublish(ctx, post)
+
+	return draft.ID, post.ID, nil
+}
+
+// publishDraft stores the post of draft and removes the draft inside tx
+func (p *PostRepository) publishDraft(ctx context.Context, tx pgx.Tx, draft models.Draft, media []models.PostImage, holdReason *string) (models.Post, error) {
+	post, err := p.insertPost(ctx, tx, draft.UserID, models.CreatePost{TextContent: draft.TextContent, Visibility: draft.Visibility}, media, holdReason)
+	if err != nil {
+		return models.Post{}, err
+	}
+
+	// The media files now belong to the post
+	if _, err := tx.Exec(ctx, `DELETE FROM post_drafts WHERE id = $1`, draft.ID); err != nil {
+		return models.Post{}, err
+	}
+
+	return post, tx.Commit(ctx)
+}
+
+// unscheduleDraft turns the claimed scheduled post draftID back into a draft with reason
+// and commits tx
+func unscheduleDraft(ctx context.Context, tx pgx.Tx, draftID, reason string) error {
+	query := `
+		UPDATE post_drafts
+		SET publish_at = NULL, failure_reason = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	if _, err := tx.Exec(ctx, query, draftID, reason); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,245 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/radifan9/social-media-backend/pkg"
+)
+
+const (
+	// How long the confirmation link sent to the new address works
+	EmailConfirmWindow = 24 * time.Hour
+	// How long the revert link sent to the old address works
+	EmailRevertWindow = 7 * 24 * time.Hour
+)
+
+var (
+	ErrEmailTaken        = errors.New("email is already registered")
+	ErrSameEmail         = errors.New("new email is the same as the current one")
+	ErrEmailTokenInvalid = errors.New("link is invalid or has expired")
+)
+
+// RequestEmailChange starts moving userID to newEmail. Earlier pending requests are cancelled
+func (u *UserRepository) RequestEmailChange(ctx context.Context, userID, newEmail string) (models.EmailChange, error) {
+	confirmToken, confirmHash, err := pkg.NewOpaqueToken()
+	if err != nil {
+		return models.EmailChange{}, err
+	}
+	revertToken, revertHash, err := pkg.NewOpaqueToken()
+	if err != nil {
+		return models.EmailChange{}, err
+	}
+
+	// Begin transaction
+	tx, err := u.db.Begin(ctx)
+	if err != nil {
+		return models.EmailChange{}, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Println("failed to rollback transaction:", rollbackErr)
+			}
+		}
+	}()
+
+	change := models.EmailChange{NewEmail: newEmail, ConfirmToken: confirmToken, RevertToken: revertToken}
+	if err = tx.QueryRow(ctx, `SELECT email FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&change.OldEmail); err != nil {
+		return models.EmailChange{}, err
+	}
+	if strings.EqualFold(change.OldEmail, newEmail) {
+		err = ErrSameEmail
+		return models.EmailChange{}, err
+	}
+
+	var taken bool
+	if err = tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE LOWER(email) = LOWER($1))`, newEmail).Scan(&taken); err != nil {
+		return models.EmailChange{}, err
+	}
+	if taken {
+		err = ErrEmailTaken
+		return models.EmailChange{}, err
+	}
+
+	cancelQuery := `
+		UPDATE email_changes
+		SET cancelled_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND confirmed_at IS NULL AND reverted_at IS NULL AND cancelled_at IS NULL
+	`
+	if _, err = tx.Exec(ctx, cancelQuery, userID); err != nil {
+		return models.EmailChange{}, err
+	}
+
+	insertQuery := `
+		INSERT INTO email_changes (user_id, old_email, new_email, confirm_token_hash, revert_token_hash)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	if _, err = tx.Exec(ctx, insertQuery, userID, change.OldEmail, newEmail, confirmHash, revertHash); err != nil {
+		return models.EmailChange{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return models.EmailChange{}, err
+	}
+
+	return change, nil
+}
+
+// CancelEmailChange drops the pending change behind a confirmation token, used when the
+// confirmation email could not be sent so no unreachable request stays open
+func (u *UserRepository) CancelEmailChange(ctx context.Context, confirmToken string) error {
+	query := `
+		UPDATE email_changes
+		SET cancelled_at = CURRENT_TIMESTAMP
+		WHERE confirm_token_hash = $1 AND confirmed_at IS NULL AND reverted_at IS NULL AND cancelled_at IS NULL
+	`
+	_, err := u.db.Exec(ctx, query, pkg.HashOpaqueToken(confirmToken))
+	return err
+}
+
+// CheckEmailConfirmToken returns the address a confirmation token would switch to, without applying it
+func (u *UserRepository) CheckEmailConfirmToken(ctx context.Context, token string) (string, error) {
+	query := `
+		SELECT new_email
+		FROM email_changes
+		WHERE confirm_token_hash = $1
+			AND confirmed_at IS NULL AND reverted_at IS NULL AND cancelled_at IS NULL
+			AND created_at > $2
+	`
+	var newEmail string
+	if err := u.db.QueryRow(ctx, query, pkg.HashOpaqueToken(token), time.Now().Add(-EmailConfirmWindow)).Scan(&newEmail); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrEmailTokenInvalid
+		}
+		return "", err
+	}
+	return newEmail, nil
+}
+
+// ConfirmEmailChange applies the change behind a confirmation token
+func (u *UserRepository) ConfirmEmailChange(ctx context.Context, token string) (string, error) {
+	// Begin transaction
+	tx, err := u.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Println("failed to rollback transaction:", rollbackErr)
+			}
+		}
+	}()
+
+	var changeID, userID, newEmail string
+	findQuery := `
+		SELECT id, user_id, new_email
+		FROM email_changes
+		WHERE confirm_token_hash = $1
+			AND confirmed_at IS NULL AND reverted_at IS NULL AND cancelled_at IS NULL
+			AND created_at > $2
+		FOR UPDATE
+	`
+	if err = tx.QueryRow(ctx, findQuery, pkg.HashOpaqueToken(token), time.Now().Add(-EmailConfirmWindow)).Scan(&changeID, &userID, &newEmail); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrEmailTokenInvalid
+		}
+		return "", err
+	}
+
+	if _, err = tx.Exec(ctx, `UPDATE users SET email = $2 WHERE id = $1`, userID, newEmail); err != nil {
+		if isUniqueViolation(err, "users_email_key") {
+			err = ErrEmailTaken
+		}
+		return "", err
+	}
+
+	if _, err = tx.Exec(ctx, `UPDATE email_changes SET confirmed_at = CURRENT_TIMESTAMP WHERE id = $1`, changeID); err != nil {
+		return "", err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return newEmail, nil
+}
+
+// CheckEmailRevertToken returns the address a revert token would restore, without applying it
+func (u *UserRepository) CheckEmailRevertToken(ctx context.Context, token string) (string, error) {
+	query := `
+		SELECT old_email
+		FROM email_changes
+		WHERE revert_token_hash = $1
+			AND reverted_at IS NULL AND cancelled_at IS NULL
+			AND created_at > $2
+	`
+	var oldEmail string
+	if err := u.db.QueryRow(ctx, query, pkg.HashOpaqueToken(token), time.Now().Add(-EmailRevertWindow)).Scan(&oldEmail); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrEmailTokenInvalid
+		}
+		return "", err
+	}
+	return oldEmail, nil
+}
+
+// RevertEmailChange undoes or cancels the change behind a revert token and returns the user id,
+// so the caller can sign the user out in case the change was not theirs
+func (u *UserRepository) RevertEmailChange(ctx context.Context, token string) (string, error) {
+	// Begin transaction
+	tx, err := u.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Println("failed to rollback transaction:", rollbackErr)
+			}
+		}
+	}()
+
+	var changeID, userID, oldEmail string
+	var confirmed bool
+	findQuery := `
+		SELECT id, user_id, old_email, confirmed_at IS NOT NULL
+		FROM email_changes
+		WHERE revert_token_hash = $1
+			AND reverted_at IS NULL AND cancelled_at IS NULL
+			AND created_at > $2
+		FOR UPDATE
+	`
+	if err = tx.QueryRow(ctx, findQuery, pkg.HashOpaqueToken(token), time.Now().Add(-EmailRevertWindow)).Scan(&changeID, &userID, &oldEmail, &confirmed); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrEmailTokenInvalid
+		}
+		return "", err
+	}
+
+	// A change that was never confirmed only needs to be cancelled
+	if confirmed {
+		if _, err = tx.Exec(ctx, `UPDATE users SET email = $2 WHERE id = $1`, userID, oldEmail); err != nil {
+			if isUniqueViolation(err, "users_email_key") {
+				err = ErrEmailTaken
+			}
+			return "", err
+		}
+	}
+
+	if _, err = tx.Exec(ctx, `UPDATE email_changes SET reverted_at = CURRENT_TIMESTAMP WHERE id = $1`, changeID); err != nil {
+		return "", err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/radifan9/social-media-backend/internal/models"
+)
+
+// exportSections maps each file of a data export to a query returning a JSON array for $1
+var exportSections = []struct {
+	name  string
+	query string
+}{
+	{"profile.json", `
+		SELECT u.id, u.email, u.created_at, up.username, up.name, up.bio, up.avatar, up.is_private, up.updated_at
+		FROM users u
+		LEFT JOIN user_profiles up ON u.id = up.user_id
+		WHERE u.id = $1
+	`},
+	{"posts.json", `
+		SELECT
+			p.id,
+			p.text_content,
+			p.visibility,
+			p.reposted_post_id,
+			p.quoted_post_id,
+			p.created_at,
+			p.hidden_at,
+			COALESCE((
+				SELECT JSON_AGG(
+					JSONB_BUILD_OBJECT(
+						'media_url', pi.media_url,
+						'media_type', pi.media_type,
+						'poster_url', pi.poster_url,
+						'alt_text', pi.alt_text,
+						'position', pi.position
+					) ORDER BY pi.position
+				)
+				FROM post_images pi
+				WHERE pi.post_id = p.id
+			), '[]') as media
+		FROM posts p
+		WHERE p.user_id = $1
+		ORDER BY p.created_at
+	`},
+	{"comments.json", `
+		SELECT id, post_id, comment, created_at, hidden_at
+		FROM post_comments
+		WHERE user_id = $1
+		ORDER BY created_at
+	`},
+	{"likes.json", `
+		SELECT post_id, created_at
+		FROM post_likes
+		WHERE user_id = $1
+		ORDER BY created_at
+	`},
+	{"poll_votes.json", `
+		SELECT pv.post_id, po.label as option, pv.created_at
+		FROM poll_votes pv
+		INNER JOIN poll_options po ON pv.option_id = po.id
+		WHERE pv.user_id = $1
+		ORDER BY pv.created_at
+	`},
+	{"bookmarks.json", `
+		SELECT post_id, created_at
+		FROM bookmarks
+		WHERE user_id = $1
+		ORDER BY created_at
+	`},
+	{"drafts.json", `
+		SELECT id, text_content, visibility, media, publish_at, failure_reason, created_at, updated_at
+		FROM post_drafts
+		WHERE user_id = $1
+		ORDER BY created_at
+	`},
+	{"messages.json", `
+		SELECT id, conversation_id, body, created_at
+		FROM messages
+		WHERE sender_id = $1
+		ORDER BY created_at
+	`},
+	{"close_friends.json", `
+		SELECT cf.friend_id, up.username, cf.created_at
+		FROM close_friends cf
+		LEFT JOIN user_profiles up ON cf.friend_id = up.user_id
+		WHERE cf.user_id = $1
+		ORDER BY cf.created_at
+	`},
+	{"following.json", `
+		SELECT uf.user_id, up.username, uf.created_at
+		FROM user_followers uf
+		LEFT JOIN user_profiles up ON uf.user_id = up.user_id
+		WHERE uf.follower_id = $1
+		ORDER BY uf.created_at
+	`},
+	{"followers.json", `
+		SELECT uf.follower_id as user_id, up.username, uf.created_at
+		FROM user_followers uf
+		LEFT JOIN user_profiles up ON uf.follower_id = up.user_id
+		WHERE uf.user_id = $1
+		ORDER BY uf.created_at
+	`},
+	{"notifications.json", `
+		SELECT id, actor_id, type, post_id, comment_id, read_at, created_at
+		FROM notifications
+		WHERE recipient_id = $1
+		ORDER BY created_at
+	`},
+}
+
+// ExportUserData collects everything stored about userID as JSON documents,
+// along with the paths of their uploaded files
+func (u *UserRepository) ExportUserData(ctx context.Context, userID string) (models.DataExport, error) {
+	export := models.DataExport{Sections: make(map[string]json.RawMessage, len(exportSections))}
+
+	for _, section := range exportSections {
+		query := `SELECT COALESCE(JSON_AGG(t), '[]') FROM (` + section.query + `) t`
+
+		var doc json.RawMessage
+		if err := u.db.QueryRow(ctx, query, userID).Scan(&doc); err != nil {
+			return models.DataExport{}, err
+		}
+		export.Sections[section.name] = doc
+	}
+
+	filesQuery := `
+		SELECT 'public/post_images', pi.media_url
+		FROM post_images pi
+		INNER JOIN posts p ON pi.post_id = p.id
+		WHERE p.user_id = $1
+		UNION ALL
+		SELECT 'public/post_images', pi.poster_url
+		FROM post_images pi
+		INNER JOIN posts p ON pi.post_id = p.id
+		WHERE p.user_id = $1 AND pi.poster_url IS NOT NULL
+		UNION ALL
+		SELECT 'public/post_images', dm->>'media_url'
+		FROM post_drafts d, jsonb_array_elements(d.media) dm
+		WHERE d.user_id = $1
+		UNION ALL
+		SELECT 'public/avatars', avatar
+		FROM user_profiles
+		WHERE user_id = $1 AND avatar IS NOT NULL AND avatar <> ''
+	`
+	rows, err := u.db.Query(ctx, filesQuery, userID)
+	if err != nil {
+		return models.DataExport{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var dir, name string
+		if err := rows.Scan(&dir, &name); err != nil {
+			return models.DataExport{}, err
+		}
+		export.MediaFiles = append(export.MediaFiles, filepath.Join(dir, filepath.Base(name)))
+	}
+
+	return export, rows.Err()
+}
@@ -0,0 +1,72 @@
+package repositories
+
+import "fmt"
+
+// SQL conditions shared by queries that must respect blocks and mutes.
+// Arguments are SQL expressions such as "$1" or "p.user_id"
+
+// notBlockedSQL is true when neither user has blocked the other
+func notBlockedSQL(a, b string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM user_blocks ub
+		WHERE (ub.blocker_id = %[1]s AND ub.blocked_id = %[2]s)
+			OR (ub.blocker_id = %[2]s AND ub.blocked_id = %[1]s)
+	)`, a, b)
+}
+
+// notMutedSQL is true when muter has not muted muted
+func notMutedSQL(muter, muted string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM user_mutes um
+		WHERE um.muter_id = %s AND um.muted_id = %s
+	)`, muter, muted)
+}
+
+// notDeletedSQL is true unless user deleted their account and is waiting to be purged
+func notDeletedSQL(user string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM users du
+		WHERE du.id = %s AND du.deleted_at IS NOT NULL
+	)`, user)
+}
+
+// accountActiveSQL is true when user is neither banned, suspended nor waiting to be purged.
+// A suspension counts as lifted once suspended_until passed
+func accountActiveSQL(user string) string {
+	return fmt.Sprintf(`EXISTS (
+		SELECT 1 FROM users au
+		WHERE au.id = %s AND au.deleted_at IS NULL
+			AND (au.status = 'active' OR (au.status = 'suspended' AND au.suspended_until <= CURRENT_TIMESTAMP))
+	)`, user)
+}
+
+// canViewAuthorSQL is true when viewer may see content of author:
+// the author has not deleted their account, no block between them,
+// and the author is public, the viewer, or followed by the viewer
+func canViewAuthorSQL(viewer, author string) string {
+	return fmt.Sprintf(`(%[3]s AND %[4]s AND (
+		%[2]s = %[1]s
+		OR NOT EXISTS (SELECT 1 FROM user_profiles vp WHERE vp.user_id = %[2]s AND vp.is_private)
+		OR EXISTS (SELECT 1 FROM user_followers vf WHERE vf.user_id = %[2]s AND vf.follower_id = %[1]s)
+	))`, viewer, author, notBlockedSQL(viewer, author), notDeletedSQL(author))
+}
+
+// postAudienceSQL is true when viewer is in the audience the author picked for the post
+// with alias post: everyone, the author's followers, their close friends or the users
+// mentioned in the post. Authors always see their own posts. It does not check blocks
+// or private accounts, pair it with canViewAuthorSQL
+func postAudienceSQL(viewer, post string) string {
+	return fmt.Sprintf(`(
+		%[2]s.visibility = 'public'
+		OR %[2]s.user_id = %[1]s
+		OR (%[2]s.visibility = 'followers' AND EXISTS (
+			SELECT 1 FROM user_followers af WHERE af.user_id = %[2]s.user_id AND af.follower_id = %[1]s
+		))
+		OR (%[2]s.visibility = 'close_friends' AND EXISTS (
+			SELECT 1 FROM close_friends acf WHERE acf.user_id = %[2]s.user_id AND acf.friend_id = %[1]s
+		))
+		OR (%[2]s.visibility = 'mentioned' AND EXISTS (
+			SELECT 1 FROM mentions am WHERE am.post_id = %[2]s.id AND am.comment_id IS NULL AND am.user_id = %[1]s
+		))
+	)`, viewer, post)
+}
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/radifan9/social-media-backend/internal/models"
+)
+
+var ErrFollowRequestNotFound = errors.New("follow request not found")
+
+// GetFollowRequests lists pending requests to follow userID, newest first
+func (u *UserRepository) GetFollowRequests(ctx context.Context, userID string) ([]models.UserSummary, error) {
+	query := `
+		SELECT up.user_id, up.username, up.name, up.bio, up.avatar
+		FROM follow_requests fr
+		INNER JOIN user_profiles up ON fr.requester_id = up.user_id
+		WHERE fr.target_id = $1
+		ORDER BY fr.created_at DESC
+	`
+	return u.queryUserSummaries(ctx, query, userID)
+}
+
+// ApproveFollowRequest turns the pending request of requesterID into a follow of userID.
+// A request between users who blocked each other cannot be approved
+func (u *UserRepository) ApproveFollowRequest(ctx context.Context, userID, requesterID string) error {
+	// Begin transaction
+	tx, err := u.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Println("failed to rollback transaction:", rollbackErr)
+			}
+		}
+	}()
+
+	deleteQuery := `
+		DELETE FROM follow_requests
+		WHERE requester_id = $1 AND target_id = $2
+			AND ` + notBlockedSQL("$1", "$2")
+	tag, err := tx.Exec(ctx, deleteQuery, requesterID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		err = ErrFollowRequestNotFound
+		return err
+	}
+
+	followQuery := `
+		INSERT INTO user_followers (user_id, follower_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, follower_id) DO NOTHING
+	`
+	if _, err = tx.Exec(ctx, followQuery, userID, requesterID); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	// The new follower's feed now includes this account
+	if err := u.rdb.Del(ctx, feedCacheKey(requesterID)).Err(); err != nil {
+		log.Printf("Failed to invalidate feed cache after approval: %v", err)
+	}
+
+	return nil
+}
+
+func (u *UserRepository) RejectFollowRequest(ctx context.Context, userID, requesterID string) error {
+	query := `DELETE FROM follow_requests WHERE requester_id = $1 AND target_id = $2`
+	tag, err := u.db.Exec(ctx, query, requesterID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrFollowRequestNotFound
+	}
+	return nil
+}
+
+// approveAllFollowRequests accepts every pending request, used when an account goes public
+func (u *UserRepository) approveAllFollowRequests(ctx context.Context, userID string) error {
+	query := `
+		WITH approved AS (
+			DELETE FROM follow_requests
+			WHERE target_id = $1
+			RETURNING requester_id
+		)
+		INSERT INTO user_followers (user_id, follower_id)
+		SELECT $1, requester_id FROM approved
+		ON CONFLICT (user_id, follower_id) DO NOTHING
+	`
+	_, err := u.db.Exec(ctx, query, userID)
+	return err
+}
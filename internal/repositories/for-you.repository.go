@@ -0,0 +1,173 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/radifan9/social-media-backend/internal/ranking"
+	"github.com/radifan9/social-media-backend/pkg"
+)
+
+const (
+	// Only posts this recent are considered for the "For You" feed
+	forYouCandidateWindow = 72 * time.Hour
+	forYouMaxCandidates   = 500
+	// Interactions of the viewer with an author older than this are forgotten
+	forYouAffinityWindow = 30 * 24 * time.Hour
+	// A ranking is reused this long, so paging through it stays stable
+	forYouCacheTTL = 10 * time.Minute
+)
+
+func forYouCacheKey(userID string) string {
+	return fmt.Sprintf("sosmed:foryou:%s", userID)
+}
+
+// forYouSnapshot is a cached ranking of the candidates of one viewer
+type forYouSnapshot struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Ranked      []ranking.Scored `json:"ranked"`
+}
+
+// GetForYouFeed returns one page of the ranked feed of userID. The ranking is computed once
+// and cached, the cursor points into it
+func (p *PostRepository) GetForYouFeed(ctx context.Context, userID string, weights ranking.Weights, cursorScore *float64, cursorID *string) (models.PostPage, error) {
+	cacheKey := forYouCacheKey(userID)
+
+	var snapshot forYouSnapshot
+	if !p.cacheManager.GetFromCache(ctx, cacheKey, &snapshot) {
+		now := time.Now()
+		candidates, err := p.queryForYouCandidates(ctx, userID, now, weights)
+		if err != nil {
+			return models.PostPage{}, err
+		}
+
+		snapshot = forYouSnapshot{GeneratedAt: now, Ranked: ranking.Rank(candidates, now, weights)}
+		p.cacheManager.SetCache(ctx, cacheKey, snapshot, forYouCacheTTL)
+	}
+
+	start := forYouPageStart(snapshot.Ranked, cursorScore, cursorID)
+	end := min(start+pageSize, len(snapshot.Ranked))
+
+	postIDs := make([]string, 0, end-start)
+	for _, s := range snapshot.Ranked[start:end] {
+		postIDs = append(postIDs, s.PostID)
+	}
+
+	var nextCursor string
+	if end < len(snapshot.Ranked) {
+		last := snapshot.Ranked[end-1]
+		nextCursor = pkg.EncodeScoreCursor(last.Score, last.PostID)
+	}
+
+	posts, err := p.GetFeedPostsByIDs(ctx, userID, postIDs)
+	if err != nil {
+		return models.PostPage{}, err
+	}
+
+	return models.PostPage{Posts: posts, NextCursor: nextCursor}, nil
+}
+
+// forYouPageStart finds where the page after the cursor begins. When the ranking was recomputed
+// and the cursor post is gone, it continues after the cursor score
+func forYouPageStart(ranked []ranking.Scored, cursorScore *float64, cursorID *string) int {
+	if cursorScore == nil {
+		return 0
+	}
+
+	for i, s := range ranked {
+		if s.PostID == *cursorID {
+			return i + 1
+		}
+	}
+	for i, s := range ranked {
+		if s.Score < *cursorScore || (s.Score == *cursorScore && s.PostID < *cursorID) {
+			return i
+		}
+	}
+	return len(ranked)
+}
+
+// queryForYouCandidates collects recent posts by followed users and by users they follow,
+// with the signals the ranking needs, all measured as of now
+func (p *PostRepository) queryForYouCandidates(ctx context.Context, userID string, now time.Time, weights ranking.Weights) ([]ranking.Candidate, error) {
+	velocitySince := now.Add(-time.Duration(weights.VelocityWindowHours * float64(time.Hour)))
+
+	query := `
+		WITH following AS (
+			SELECT user_id FROM user_followers WHERE follower_id = $1
+		),
+		second_degree AS (
+			SELECT uf.user_id, COUNT(*) as mutual
+			FROM user_followers uf
+			WHERE uf.follower_id IN (SELECT user_id FROM following)
+				AND uf.user_id <> $1
+			GROUP BY uf.user_id
+		)
+		SELECT
+			p.id,
+			p.user_id,
+			p.created_at,
+			(SELECT COUNT(*) FROM post_likes pl WHERE pl.post_id = p.id AND pl.created_at > $4) as recent_likes,
+			(
+				SELECT COUNT(*) FROM post_comments pc
+				WHERE pc.post_id = p.id AND pc.hidden_at IS NULL AND pc.created_at > $4
+			) as recent_comments,
+			(
+				SELECT COUNT(*) FROM post_likes il
+				INNER JOIN posts ip ON il.post_id = ip.id
+				WHERE il.user_id = $1 AND ip.user_id = p.user_id AND il.created_at > $5
+			) + (
+				SELECT COUNT(*) FROM post_comments ic
+				INNER JOIN posts ip ON ic.post_id = ip.id
+				WHERE ic.user_id = $1 AND ip.user_id = p.user_id AND ic.created_at > $5
+			) as interactions,
+			p.user_id IN (SELECT user_id FROM following) as follows_author,
+			COALESCE(sd.mutual, 0) as mutual_followers
+		FROM posts p
+		LEFT JOIN second_degree sd ON p.user_id = sd.user_id
+		WHERE p.created_at > $2 AND p.created_at <= $3
+			AND p.user_id <> $1
+			AND p.reposted_post_id IS NULL
+			AND p.hidden_at IS NULL
+			AND (p.user_id IN (SELECT user_id FROM following) OR sd.user_id IS NOT NULL)
+			AND ` + notMutedSQL("$1", "p.user_id") + `
+			AND ` + canViewAuthorSQL("$1", "p.user_id") + `
+			AND ` + postAudienceSQL("$1", "p") + `
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $6
+	`
+
+	rows, err := p.db.Query(ctx, query,
+		userID, now.Add(-forYouCandidateWindow), now, velocitySince, now.Add(-forYouAffinityWindow), forYouMaxCandidates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []ranking.Candidate
+	for rows.Next() {
+		var c ranking.Candidate
+		if err := rows.Scan(
+			&c.PostID,
+			&c.AuthorID,
+			&c.CreatedAt,
+			&c.RecentLikes,
+			&c.RecentComments,
+			&c.Interactions,
+			&c.FollowsAuthor,
+			&c.MutualFollowers,
+		); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Ranked %d for you candidates for user: %s", len(candidates), userID)
+	return candidates, nil
+}
@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/radifan9/social-media-backend/internal/utils"
+)
+
+// saveEntities stores the hashtags and mentions found in a post or comment text
+// and notifies mentioned users unless notify is false. commentID is nil for the post text itself
+func (p *PostRepository) saveEntities(ctx context.Context, tx pgx.Tx, actorID, postID string, commentID *string, text string, notify bool) ([]models.Entity, error) {
+	entities := utils.ParseEntities(text)
+	if len(entities) == 0 {
+		return []models.Entity{}, nil
+	}
+
+	var handles []string
+	for _, e := range entities {
+		if e.Type == models.EntityMention {
+			handles = append(handles, strings.ToLower(e.Text))
+		}
+	}
+
+	mentioned, err := p.resolveMentions(ctx, tx, actorID, handles)
+	if err != nil {
+		return nil, err
+	}
+
+	notified := make(map[string]bool)
+	for i := range entities {
+		e := &entities[i]
+
+		switch e.Type {
+		case models.EntityHashtag:
+			hashtagQuery := `
+				INSERT INTO hashtags (tag)
+				VALUES ($1)
+				ON CONFLICT (tag) DO UPDATE SET tag = EXCLUDED.tag
+				RETURNING id
+			`
+			var hashtagID string
+			if err := tx.QueryRow(ctx, hashtagQuery, e.Text).Scan(&hashtagID); err != nil {
+				return nil, err
+			}
+
+			linkQuery := `
+				INSERT INTO post_hashtags (post_id, comment_id, hashtag_id, start_index, end_index)
+				VALUES ($1, $2, $3, $4, $5)
+			`
+			if _, err := tx.Exec(ctx, linkQuery, postID, commentID, hashtagID, e.Start, e.End); err != nil {
+				return nil, err
+			}
+
+		case models.EntityMention:
+			// Unknown handles stay plain text
+			userID, ok := mentioned[strings.ToLower(e.Text)]
+			if !ok {
+				continue
+			}
+			e.UserID = &userID
+
+			mentionQuery := `
+				INSERT INTO mentions (post_id, comment_id, user_id, handle, start_index, end_index)
+				VALUES ($1, $2, $3, $4, $5, $6)
+			`
+			if _, err := tx.Exec(ctx, mentionQuery, postID, commentID, userID, e.Text, e.Start, e.End); err != nil {
+				return nil, err
+			}
+
+			if !notify || userID == actorID || notified[userID] {
+				continue
+			}
+			notified[userID] = true
+
+			// Users who muted the actor or are outside the audience of the post do not get
+			// notified. Users mentioned in a post for mentioned users are in its audience
+			notifQuery := `
+				INSERT INTO notifications (recipient_id, actor_id, type, post_id, comment_id)
+				SELECT $1::uuid, $2::uuid, 'mention'::notification_type, $3::uuid, $4::uuid
+				FROM posts np
+				WHERE np.id = $3::uuid
+					AND ` + notMutedSQL("$1::uuid", "$2::uuid") + `
+					AND ` + postAudienceSQL("$1::uuid", "np")
+
+			if _, err := tx.Exec(ctx, notifQuery, userID, actorID, postID, commentID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return entities, nil
+}
+
+// resolveMentions maps lowercased handles to user ids.
+// Users in a block with the actor cannot be mentioned and are left out
+func (p *PostRepository) resolveMentions(ctx context.Context, tx pgx.Tx, actorID string, handles []string) (map[string]string, error) {
+	mentioned := make(map[string]string)
+	if len(handles) == 0 {
+		return mentioned, nil
+	}
+
+	query := `
+		SELECT LOWER(username), user_id
+		FROM user_profiles
+		WHERE LOWER(username) = ANY($1)
+			AND ` + notDeletedSQL("user_id") + `
+			AND ` + notBlockedSQL("$2::uuid", "user_id")
+
+	rows, err := tx.Query(ctx, query, handles, actorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var handle, userID string
+		if err := rows.Scan(&handle, &userID); err != nil {
+			return nil, err
+		}
+		mentioned[handle] = userID
+	}
+
+	return mentioned, rows.Err()
+}
+
+// GetHashtagPosts returns posts whose text uses the tag, newest first
+func (p *PostRepository) GetHashtagPosts(ctx context.Context, viewerID, tag string, cursorTime *time.Time, cursorID *string) (models.PostPage, error) {
+	query := `
+		SELECT p.id, p.created_at
+		FROM posts p
+		WHERE EXISTS (
+				SELECT 1
+				FROM post_hashtags ph
+				INNER JOIN hashtags h ON ph.hashtag_id = h.id
+				WHERE ph.post_id = p.id AND ph.comment_id IS NULL AND h.tag = $1
+			)
+			AND p.hidden_at IS NULL
+			AND ($2::timestamptz IS NULL OR (p.created_at, p.id) < ($2, $3::uuid))
+			AND ` + canViewAuthorSQL("$5::uuid", "p.user_id") + `
+			AND ` + postAudienceSQL("$5::uuid", "p") + `
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $4
+	`
+
+	return p.queryPostPage(ctx, viewerID, query, strings.ToLower(tag), cursorTime, cursorID, pageSize+1, viewerID)
+}
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/radifan9/social-media-backend/pkg"
+)
+
+type MediaRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMediaRepository(db *pgxpool.Pool) *MediaRepository {
+	return &MediaRepository{db: db}
+}
+
+// ClaimPendingMedia marks up to limit pending gifs and videos as processing and returns them.
+// Rows stuck in processing for more than 10 minutes are claimed again, SKIP LOCKED keeps replicas apart
+func (m *MediaRepository) ClaimPendingMedia(ctx context.Context, limit int) ([]models.MediaJob, error) {
+	query := `
+		UPDATE post_images pi
+		SET status = 'processing', claimed_at = CURRENT_TIMESTAMP
+		FROM posts p
+		WHERE pi.post_id = p.id
+			AND pi.id IN (
+				SELECT id FROM post_images
+				WHERE status = 'pending'
+					OR (status = 'processing' AND claimed_at < CURRENT_TIMESTAMP - INTERVAL '10 minutes')
+				ORDER BY created_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+		RETURNING pi.id, pi.post_id, p.user_id, pi.media_url, pi.media_type
+	`
+
+	rows, err := m.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []models.MediaJob
+	for rows.Next() {
+		var job models.MediaJob
+		if err := rows.Scan(&job.ID, &job.PostID, &job.UserID, &job.MediaURL, &job.MediaType); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+func (m *MediaRepository) MarkMediaReady(ctx context.Context, id string, info pkg.MediaInfo, posterURL string) error {
+	query := `
+		UPDATE post_images
+		SET status = 'ready',
+			poster_url = $2,
+			duration_ms = $3,
+			width = $4,
+			height = $5,
+			error = NULL,
+			processed_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+
+	_, err := m.db.Exec(ctx, query, id, posterURL, info.Duration.Milliseconds(), info.Width, info.Height)
+	return err
+}
+
+func (m *MediaRepository) MarkMediaFailed(ctx context.Context, id, reason string) error {
+	query := `
+		UPDATE post_images
+		SET status = 'failed', error = $2, processed_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+
+	_, err := m.db.Exec(ctx, query, id, reason)
+	return err
+}
+
+// ListReferencedMedia returns the base file names still referenced by posts and profiles
+func (m *MediaRepository) ListReferencedMedia(ctx context.Context) (map[string]struct{}, error) {
+	query := `
+		SELECT media_url FROM post_images
+		UNION
+		SELECT poster_url FROM post_images WHERE poster_url IS NOT NULL
+		UNION
+		SELECT jsonb_array_elements(media)->>'media_url' FROM post_drafts
+		UNION
+		SELECT avatar FROM user_profiles WHERE avatar IS NOT NULL AND avatar <> ''
+	`
+
+	rows, err := m.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Older rows store the full "public/..." path, newer ones only the file name
+	referenced := make(map[string]struct{})
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		referenced[filepath.Base(path)] = struct{}{}
+	}
+
+	return referenced, rows.Err()
+}
@@ -0,0 +1,543 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/radifan9/social-media-backend/pkg"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrConversationNotFound = errors.New("conversation not found")
+	ErrMessageNotFound      = errors.New("message not found")
+	ErrParticipantsBlocked  = errors.New("some participants cannot be in a conversation together")
+)
+
+type MessageRepository struct {
+	db  *pgxpool.Pool
+	rdb *redis.Client
+}
+
+func NewMessageRepository(db *pgxpool.Pool, rdb *redis.Client) *MessageRepository {
+	return &MessageRepository{
+		db:  db,
+		rdb: rdb,
+	}
+}
+
+// messageChannel is the Redis pub/sub channel with the message events of userID
+func messageChannel(userID string) string {
+	return fmt.Sprintf("sosmed:dm:%s", userID)
+}
+
+// conversationVisibleSQL is true when viewer takes part in conversation and, for a 1:1
+// conversation, the other user still exists and is not in a block with viewer
+func conversationVisibleSQL(viewer, conversation string) string {
+	return fmt.Sprintf(`(EXISTS (
+		SELECT 1 FROM conversation_participants vcp
+		WHERE vcp.conversation_id = %[2]s AND vcp.user_id = %[1]s
+	) AND NOT EXISTS (
+		SELECT 1 FROM conversations dc
+		INNER JOIN conversation_participants ocp ON ocp.conversation_id = dc.id
+		WHERE dc.id = %[2]s AND NOT dc.is_group AND ocp.user_id <> %[1]s
+			AND NOT (%[3]s AND %[4]s)
+	))`, viewer, conversation, notBlockedSQL(viewer, "ocp.user_id"), notDeletedSQL("ocp.user_id"))
+}
+
+// canSendSQL is true when sender may write in conversation, on top of conversationVisibleSQL.
+// In a 1:1 conversation the rules of starting one apply on every message, so a private
+// account that was unfollowed stops receiving messages, unless it started the conversation
+func canSendSQL(sender, conversation string) string {
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM conversations sc
+		INNER JOIN conversation_participants scp ON scp.conversation_id = sc.id
+		WHERE sc.id = %[2]s AND NOT sc.is_group AND scp.user_id <> %[1]s
+			AND sc.created_by IS DISTINCT FROM scp.user_id
+			AND NOT %[3]s
+	)`, sender, conversation, canViewAuthorSQL(sender, "scp.user_id"))
+}
+
+// messageVisibleSQL is true when viewer may read messages of sender. In groups,
+// messages of users in a block with the viewer are left out
+func messageVisibleSQL(viewer, sender string) string {
+	return fmt.Sprintf(`(%s AND %s)`, notBlockedSQL(viewer, sender), notDeletedSQL(sender))
+}
+
+// conversationSQL selects a conversation c as seen by $1, scanned by scanConversation
+func conversationSQL() string {
+	return `
+		SELECT
+			c.id,
+			c.is_group,
+			c.name,
+			c.created_at,
+			c.last_message_at,
+			COALESCE((
+				SELECT JSON_AGG(
+					JSONB_BUILD_OBJECT(
+						'user_id', cp.user_id,
+						'username', up.username,
+						'name', up.name,
+						'bio', up.bio,
+						'avatar', up.avatar,
+						'last_read_at', CASE WHEN ` + notBlockedSQL("$1", "cp.user_id") + ` THEN cp.last_read_at END
+					) ORDER BY cp.joined_at, cp.user_id
+				)
+				FROM conversation_participants cp
+				LEFT JOIN user_profiles up ON cp.user_id = up.user_id
+				WHERE cp.conversation_id = c.id AND ` + notDeletedSQL("cp.user_id") + `
+			), '[]') as participants,
+			(
+				SELECT JSONB_BUILD_OBJECT(
+					'id', m.id,
+					'conversation_id', m.conversation_id,
+					'sender_id', m.sender_id,
+					'body', m.body,
+					'created_at', m.created_at
+				)
+				FROM messages m
+				WHERE m.conversation_id = c.id AND ` + messageVisibleSQL("$1", "m.sender_id") + `
+				ORDER BY m.created_at DESC, m.id DESC
+				LIMIT 1
+			) as last_message,
+			(
+				SELECT COUNT(*)
+				FROM messages m
+				INNER JOIN conversation_participants me ON me.conversation_id = m.conversation_id AND me.user_id = $1
+				WHERE m.conversation_id = c.id
+					AND m.sender_id <> $1
+					AND (me.last_read_at IS NULL OR m.created_at > me.last_read_at)
+					AND ` + messageVisibleSQL("$1", "m.sender_id") + `
+			) as unread_count
+		FROM conversations c
+	`
+}
+
+func scanConversation(row pgx.Row) (models.Conversation, error) {
+	var c models.Conversation
+	if err := row.Scan(
+		&c.ID, &c.IsGroup, &c.Name, &c.CreatedAt, &c.LastMessageAt, &c.Participants, &c.LastMessage, &c.UnreadCount,
+	); err != nil {
+		return models.Conversation{}, err
+	}
+	return c, nil
+}
+
+// CreateConversation starts a conversation of userID with participantIDs. With a single
+// other user it is a 1:1 conversation, and the existing one is returned if there is one.
+// Every participant has to be someone userID could see the posts of: blocked and deleted
+// users are reported as not found, private accounts need userID to follow them.
+// A group cannot bring together users who are in a block with each other
+func (m *MessageRepository) CreateConversation(ctx context.Context, userID string, participantIDs []string, name *string) (models.Conversation, error) {
+	if slices.Contains(participantIDs, userID) {
+		return models.Conversation{}, ErrSelfAction
+	}
+
+	reachQuery := `
+		SELECT
+			u.id,
+			` + notBlockedSQL("$1", "u.id") + ` AND ` + notDeletedSQL("u.id") + `,
+			` + canViewAuthorSQL("$1", "u.id") + `
+		FROM users u
+		WHERE u.id = ANY($2::uuid[])
+	`
+	rows, err := m.db.Query(ctx, reachQuery, userID, participantIDs)
+	if err != nil {
+		return models.Conversation{}, err
+	}
+	found := 0
+	private := false
+	for rows.Next() {
+		var id string
+		var reachable, canView bool
+		if err := rows.Scan(&id, &reachable, &canView); err != nil {
+			rows.Close()
+			return models.Conversation{}, err
+		}
+		if !reachable {
+			rows.Close()
+			return models.Conversation{}, ErrUserNotFound
+		}
+		private = private || !canView
+		found++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.Conversation{}, err
+	}
+	if found != len(participantIDs) {
+		return models.Conversation{}, ErrUserNotFound
+	}
+	if private {
+		return models.Conversation{}, ErrPrivateAccount
+	}
+
+	// Blocks between userID and the others are covered above
+	if len(participantIDs) > 1 {
+		var blocked bool
+		blockQuery := `
+			SELECT EXISTS (
+				SELECT 1 FROM user_blocks ub
+				WHERE ub.blocker_id = ANY($1::uuid[]) AND ub.blocked_id = ANY($1::uuid[])
+			)
+		`
+		if err := m.db.QueryRow(ctx, blockQuery, participantIDs).Scan(&blocked); err != nil {
+			return models.Conversation{}, err
+		}
+		if blocked {
+			return models.Conversation{}, ErrParticipantsBlocked
+		}
+	}
+
+	// Begin transaction
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return models.Conversation{}, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Println("failed to rollback transaction:", rollbackErr)
+			}
+		}
+	}()
+
+	var conversationID string
+	if len(participantIDs) == 1 {
+		// The no-op update makes RETURNING give the id of an existing conversation too
+		ids := []string{userID, participantIDs[0]}
+		slices.Sort(ids)
+		directQuery := `
+			INSERT INTO conversations (is_group, direct_key, created_by)
+			VALUES (false, $1, $2)
+			ON CONFLICT (direct_key) DO UPDATE SET direct_key = EXCLUDED.direct_key
+			RETURNING id
+		`
+		err = tx.QueryRow(ctx, directQuery, strings.Join(ids, ":"), userID).Scan(&conversationID)
+	} else {
+		groupQuery := `INSERT INTO conversations (is_group, name, created_by) VALUES (true, $1, $2) RETURNING id`
+		err = tx.QueryRow(ctx, groupQuery, name, userID).Scan(&conversationID)
+	}
+	if err != nil {
+		return models.Conversation{}, err
+	}
+
+	participantsQuery := `
+		INSERT INTO conversation_participants (conversation_id, user_id)
+		SELECT $1::uuid, unnest($2::uuid[])
+		ON CONFLICT (conversation_id, user_id) DO NOTHING
+	`
+	if _, err = tx.Exec(ctx, participantsQuery, conversationID, append([]string{userID}, participantIDs...)); err != nil {
+		return models.Conversation{}, err
+	}
+
+	// Commit
+	if err = tx.Commit(ctx); err != nil {
+		return models.Conversation{}, err
+	}
+
+	return m.GetConversation(ctx, userID, conversationID)
+}
+
+// GetConversation returns a conversation viewerID takes part in
+func (m *MessageRepository) GetConversation(ctx context.Context, viewerID, conversationID string) (models.Conversation, error) {
+	query := conversationSQL() + `WHERE c.id = $2 AND ` + conversationVisibleSQL("$1", "c.id")
+
+	conversation, err := scanConversation(m.db.QueryRow(ctx, query, viewerID, conversationID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Conversation{}, ErrConversationNotFound
+		}
+		return models.Conversation{}, err
+	}
+	return conversation, nil
+}
+
+// GetConversations lists the conversations of userID, most recent message first
+func (m *MessageRepository) GetConversations(ctx context.Context, userID string, cursorTime *time.Time, cursorID *string) (models.ConversationPage, error) {
+	query := conversationSQL() + `
+		WHERE ` + conversationVisibleSQL("$1", "c.id") + `
+			AND ($2::timestamptz IS NULL OR (c.last_message_at, c.id) < ($2, $3::uuid))
+		ORDER BY c.last_message_at DESC, c.id DESC
+		LIMIT $4
+	`
+
+	rows, err := m.db.Query(ctx, query, userID, cursorTime, cursorID, pageSize+1)
+	if err != nil {
+		return models.ConversationPage{}, err
+	}
+	defer rows.Close()
+
+	page := models.ConversationPage{Conversations: []models.Conversation{}}
+	for rows.Next() {
+		c, err := scanConversation(rows)
+		if err != nil {
+			return models.ConversationPage{}, err
+		}
+		page.Conversations = append(page.Conversations, c)
+	}
+	if err := rows.Err(); err != nil {
+		return models.ConversationPage{}, err
+	}
+
+	// The extra row only tells there is a next page
+	if len(page.Conversations) > pageSize {
+		page.Conversations = page.Conversations[:pageSize]
+		last := page.Conversations[pageSize-1]
+		page.NextCursor = pkg.EncodeCursor(last.LastMessageAt, last.ID)
+	}
+
+	return page, nil
+}
+
+// GetUnreadCount counts the unread messages of userID and the conversations they are in
+func (m *MessageRepository) GetUnreadCount(ctx context.Context, userID string) (models.UnreadCount, error) {
+	query := `
+		SELECT COUNT(DISTINCT m.conversation_id), COUNT(*)
+		FROM conversation_participants me
+		INNER JOIN messages m ON m.conversation_id = me.conversation_id
+		WHERE me.user_id = $1
+			AND m.sender_id <> $1
+			AND (me.last_read_at IS NULL OR m.created_at > me.last_read_at)
+			AND ` + messageVisibleSQL("$1", "m.sender_id") + `
+			AND ` + conversationVisibleSQL("$1", "me.conversation_id") + `
+	`
+
+	var unread models.UnreadCount
+	if err := m.db.QueryRow(ctx, query, userID).Scan(&unread.Conversations, &unread.Messages); err != nil {
+		return models.UnreadCount{}, err
+	}
+	return unread, nil
+}
+
+// GetMessages returns the history of a conversation of viewerID, newest first
+func (m *MessageRepository) GetMessages(ctx context.Context, viewerID, conversationID string, cursorTime *time.Time, cursorID *string) (models.MessagePage, error) {
+	if err := m.checkConversation(ctx, viewerID, conversationID); err != nil {
+		return models.MessagePage{}, err
+	}
+
+	query := `
+		SELECT m.id, m.conversation_id, m.sender_id, m.body, m.created_at
+		FROM messages m
+		WHERE m.conversation_id = $2
+			AND ` + messageVisibleSQL("$1", "m.sender_id") + `
+			AND ($3::timestamptz IS NULL OR (m.created_at, m.id) < ($3, $4::uuid))
+		ORDER BY m.created_at DESC, m.id DESC
+		LIMIT $5
+	`
+
+	rows, err := m.db.Query(ctx, query, viewerID, conversationID, cursorTime, cursorID, pageSize+1)
+	if err != nil {
+		return models.MessagePage{}, err
+	}
+	defer rows.Close()
+
+	page := models.MessagePage{Messages: []models.Message{}}
+	for rows.Next() {
+		var msg models.Message
+		if err := rows.Scan(&msg.ID, &msg.ConversationID, &msg.SenderID, &msg.Body, &msg.CreatedAt); err != nil {
+			return models.MessagePage{}, err
+		}
+		page.Messages = append(page.Messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return models.MessagePage{}, err
+	}
+
+	// The extra row only tells there is a next page
+	if len(page.Messages) > pageSize {
+		page.Messages = page.Messages[:pageSize]
+		last := page.Messages[pageSize-1]
+		page.NextCursor = pkg.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	return page, nil
+}
+
+// SendMessage posts body to a conversation of userID and pushes it to the other participants.
+// Sending counts as reading the conversation up to the new message
+func (m *MessageRepository) SendMessage(ctx context.Context, userID, conversationID, body string) (models.Message, error) {
+	if err := m.checkSend(ctx, userID, conversationID); err != nil {
+		return models.Message{}, err
+	}
+
+	// Begin transaction
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return models.Message{}, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Println("failed to rollback transaction:", rollbackErr)
+			}
+		}
+	}()
+
+	var msg models.Message
+	insertQuery := `
+		INSERT INTO messages (conversation_id, sender_id, body)
+		VALUES ($1, $2, $3)
+		RETURNING id, conversation_id, sender_id, body, created_at
+	`
+	if err = tx.QueryRow(ctx, insertQuery, conversationID, userID, body).Scan(
+		&msg.ID, &msg.ConversationID, &msg.SenderID, &msg.Body, &msg.CreatedAt,
+	); err != nil {
+		return models.Message{}, err
+	}
+
+	if _, err = tx.Exec(ctx, `UPDATE conversations SET last_message_at = GREATEST(last_message_at, $2) WHERE id = $1`, conversationID, msg.CreatedAt); err != nil {
+		return models.Message{}, err
+	}
+
+	readQuery := `
+		UPDATE conversation_participants
+		SET last_read_at = GREATEST(last_read_at, $3)
+		WHERE conversation_id = $1 AND user_id = $2
+	`
+	if _, err = tx.Exec(ctx, readQuery, conversationID, userID, msg.CreatedAt); err != nil {
+		return models.Message{}, err
+	}
+
+	// Commit
+	if err = tx.Commit(ctx); err != nil {
+		return models.Message{}, err
+	}
+
+	// Participants in a block with the sender do not see the message
+	recipientsQuery := `
+		SELECT cp.user_id
+		FROM conversation_participants cp
+		WHERE cp.conversation_id = $1 AND cp.user_id <> $2 AND ` + notBlockedSQL("cp.user_id", "$2") + `
+	`
+	m.publish(ctx, recipientsQuery, []any{conversationID, userID}, models.MessageEvent{
+		Type:           models.MessageEventMessage,
+		ConversationID: conversationID,
+		Message:        &msg,
+	})
+
+	return msg, nil
+}
+
+// MarkRead moves the read receipt of userID up to messageID, or the latest message when
+// messageID is empty, and tells the other participants. Receipts never move backwards.
+// It returns nil when the conversation has no messages yet
+func (m *MessageRepository) MarkRead(ctx context.Context, userID, conversationID, messageID string) (*time.Time, error) {
+	if err := m.checkConversation(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE conversation_participants cp
+		SET last_read_at = GREATEST(cp.last_read_at, t.created_at)
+		FROM (
+			SELECT m.created_at
+			FROM messages m
+			WHERE m.conversation_id = $1 AND ($3::text = '' OR m.id = NULLIF($3::text, '')::uuid)
+			ORDER BY m.created_at DESC
+			LIMIT 1
+		) t
+		WHERE cp.conversation_id = $1 AND cp.user_id = $2
+		RETURNING cp.last_read_at
+	`
+
+	var readAt time.Time
+	if err := m.db.QueryRow(ctx, query, conversationID, userID, messageID).Scan(&readAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			if messageID != "" {
+				return nil, ErrMessageNotFound
+			}
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Participants in a block with the reader do not get the receipt
+	recipientsQuery := `
+		SELECT cp.user_id
+		FROM conversation_participants cp
+		WHERE cp.conversation_id = $1 AND cp.user_id <> $2 AND ` + notBlockedSQL("cp.user_id", "$2") + `
+	`
+	m.publish(ctx, recipientsQuery, []any{conversationID, userID}, models.MessageEvent{
+		Type:           models.MessageEventRead,
+		ConversationID: conversationID,
+		UserID:         userID,
+		ReadAt:         &readAt,
+	})
+
+	return &readAt, nil
+}
+
+// SubscribeMessages subscribes to the message events of userID, the caller closes the subscription
+func (m *MessageRepository) SubscribeMessages(ctx context.Context, userID string) *redis.PubSub {
+	return m.rdb.Subscribe(ctx, messageChannel(userID))
+}
+
+// checkConversation returns ErrConversationNotFound unless userID may use the conversation
+func (m *MessageRepository) checkConversation(ctx context.Context, userID, conversationID string) error {
+	query := `SELECT ` + conversationVisibleSQL("$1", "$2::uuid")
+
+	var visible bool
+	if err := m.db.QueryRow(ctx, query, userID, conversationID).Scan(&visible); err != nil {
+		return err
+	}
+	if !visible {
+		return ErrConversationNotFound
+	}
+	return nil
+}
+
+// checkSend is checkConversation for sending, it returns ErrPrivateAccount when the other
+// user of a 1:1 conversation has a private account userID does not follow anymore
+func (m *MessageRepository) checkSend(ctx context.Context, userID, conversationID string) error {
+	query := `SELECT ` + conversationVisibleSQL("$1", "$2::uuid") + `, ` + canSendSQL("$1", "$2::uuid")
+
+	var visible, canSend bool
+	if err := m.db.QueryRow(ctx, query, userID, conversationID).Scan(&visible, &canSend); err != nil {
+		return err
+	}
+	if !visible {
+		return ErrConversationNotFound
+	}
+	if !canSend {
+		return ErrPrivateAccount
+	}
+	return nil
+}
+
+// publish pushes event to the channel of every user returned by recipientsQuery.
+// Delivery is best effort, the message is stored either way
+func (m *MessageRepository) publish(ctx context.Context, recipientsQuery string, args []any, event models.MessageEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode message event: %v", err)
+		return
+	}
+
+	rows, err := m.db.Query(ctx, recipientsQuery, args...)
+	if err != nil {
+		log.Printf("Failed to get recipients of conversation %s: %v", event.ConversationID, err)
+		return
+	}
+	recipients, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		log.Printf("Failed to get recipients of conversation %s: %v", event.ConversationID, err)
+		return
+	}
+
+	for _, recipientID := range recipients {
+		if err := m.rdb.Publish(ctx, messageChannel(recipientID), payload).Err(); err != nil {
+			log.Printf("Failed to publish message event to user %s: %v", recipientID, err)
+		}
+	}
+}
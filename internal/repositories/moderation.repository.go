@@ -0,0 +1,420 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/radifan9/social-media-backend/pkg"
+	"github.com/redis/go-redis/v9"
+)
+
+// Suspension length when the moderator does not pick one
+const defaultSuspendDays = 7
+
+var (
+	ErrCommentNotFound   = errors.New("comment not found")
+	ErrAlreadyReported   = errors.New("you already reported this")
+	ErrReportNotFound    = errors.New("report not found")
+	ErrReportUnavailable = errors.New("report is claimed by another moderator or already resolved")
+	ErrReportNotClaimed  = errors.New("claim the report before resolving it")
+	ErrInvalidResolution = errors.New("this action does not apply to the reported target")
+)
+
+type ModerationRepository struct {
+	db *pgxpool.Pool
+	pr *PostRepository
+	ac *AuthCacheManager
+}
+
+func NewModerationRepository(db *pgxpool.Pool, rdb *redis.Client) *ModerationRepository {
+	return &ModerationRepository{
+		db: db,
+		pr: NewPostRepository(db, rdb),
+		ac: NewAuthCacheManager(rdb),
+	}
+}
+
+const reportColumns = `
+	id, reporter_id, target_type, target_user_id, post_id, comment_id, reason, details,
+	content_snapshot, status, assigned_to, resolution, resolution_note, created_at, claimed_at, resolved_at
+`
+
+func scanReport(row pgx.Row) (models.Report, error) {
+	var r models.Report
+	err := row.Scan(
+		&r.ID, &r.ReporterID, &r.TargetType, &r.TargetUserID, &r.PostID, &r.CommentID, &r.Reason, &r.Details,
+		&r.ContentSnapshot, &r.Status, &r.AssignedTo, &r.Resolution, &r.ResolutionNote, &r.CreatedAt, &r.ClaimedAt, &r.ResolvedAt,
+	)
+	return r, err
+}
+
+// CreateReport files a report against a post, comment or user the reporter can see.
+// The reported text is copied so moderators can still read it if the author deletes it
+func (m *ModerationRepository) CreateReport(ctx context.Context, reporterID string, body models.CreateReport) (models.Report, error) {
+	var targetUserID string
+	var postID, commentID, snapshot *string
+
+	switch body.TargetType {
+	case models.ReportPost:
+		query := `
+			SELECT p.user_id, p.text_content
+			FROM posts p
+			WHERE p.id = $1
+				AND ` + canViewAuthorSQL("$2::uuid", "p.user_id") + `
+				AND ` + postAudienceSQL("$2::uuid", "p")
+		if err := m.db.QueryRow(ctx, query, body.TargetID, reporterID).Scan(&targetUserID, &snapshot); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return models.Report{}, ErrPostNotFound
+			}
+			return models.Report{}, err
+		}
+		postID = &body.TargetID
+
+	case models.ReportComment:
+		query := `
+			SELECT pc.user_id, pc.post_id, pc.comment
+			FROM post_comments pc
+			INNER JOIN posts p ON pc.post_id = p.id
+			WHERE pc.id = $1
+				AND ` + canViewAuthorSQL("$2::uuid", "p.user_id") + `
+				AND ` + postAudienceSQL("$2::uuid", "p") + `
+				AND ` + notBlockedSQL("$2::uuid", "pc.user_id")
+		var commentPostID, text string
+		if err := m.db.QueryRow(ctx, query, body.TargetID, reporterID).Scan(&targetUserID, &commentPostID, &text); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return models.Report{}, ErrCommentNotFound
+			}
+			return models.Report{}, err
+		}
+		postID, commentID, snapshot = &commentPostID, &body.TargetID, &text
+
+	case models.ReportUser:
+		query := `SELECT id FROM users WHERE id = $1`
+		if err := m.db.QueryRow(ctx, query, body.TargetID).Scan(&targetUserID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return models.Report{}, ErrUserNotFound
+			}
+			return models.Report{}, err
+		}
+	}
+
+	if targetUserID == reporterID {
+		return models.Report{}, ErrSelfAction
+	}
+
+	var details *string
+	if body.Details != "" {
+		details = &body.Details
+	}
+
+	query := `
+		INSERT INTO reports (reporter_id, target_type, target_user_id, post_id, comment_id, reason, details, content_snapshot)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + reportColumns
+
+	report, err := scanReport(m.db.QueryRow(ctx, query,
+		reporterID, body.TargetType, targetUserID, postID, commentID, body.Reason, details, snapshot,
+	))
+	if err != nil {
+		if isUniqueViolation(err, "reports_open_target_key") {
+			return models.Report{}, ErrAlreadyReported
+		}
+		return models.Report{}, err
+	}
+
+	return report, nil
+}
+
+// fileFilterReport queues content held by the content filter. It has no reporter
+func fileFilterReport(ctx context.Context, tx pgx.Tx, targetType, authorID, postID string, commentID *string, text, reason string) error {
+	query := `
+		INSERT INTO reports (target_type, target_user_id, post_id, comment_id, reason, details, content_snapshot)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := tx.Exec(ctx, query, targetType, authorID, postID, commentID, models.ReasonContentFilter, reason, text)
+	return err
+}
+
+// ListReports returns the queue in the given status, oldest first
+func (m *ModerationRepository) ListReports(ctx context.Context, status string, cursorTime *time.Time, cursorID *string) (models.ReportPage, error) {
+	query := `
+		SELECT ` + reportColumns + `
+		FROM reports
+		WHERE status = $1
+			AND ($2::timestamptz IS NULL OR (created_at, id) > ($2, $3::uuid))
+		ORDER BY created_at, id
+		LIMIT $4
+	`
+
+	rows, err := m.db.Query(ctx, query, status, cursorTime, cursorID, pageSize+1)
+	if err != nil {
+		return models.ReportPage{}, err
+	}
+	defer rows.Close()
+
+	page := models.ReportPage{Reports: []models.Report{}}
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return models.ReportPage{}, err
+		}
+
+		// The extra row only tells us another page exists
+		if len(page.Reports) == pageSize {
+			last := page.Reports[len(page.Reports)-1]
+			page.NextCursor = pkg.EncodeCursor(last.CreatedAt, last.ID)
+			break
+		}
+		page.Reports = append(page.Reports, report)
+	}
+
+	return page, rows.Err()
+}
+
+// ClaimReport assigns an open report to the moderator. Claiming a report you already hold is a no-op
+func (m *ModerationRepository) ClaimReport(ctx context.Context, moderatorID, reportID string) (models.Report, error) {
+	// Begin transaction
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return models.Report{}, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Println("failed to rollback transaction:", rollbackErr)
+			}
+		}
+	}()
+
+	claimQuery := `
+		UPDATE reports
+		SET status = 'claimed', assigned_to = $2, claimed_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'open'
+		RETURNING ` + reportColumns
+
+	report, err := scanReport(tx.QueryRow(ctx, claimQuery, reportID, moderatorID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		report, err = scanReport(tx.QueryRow(ctx, `SELECT `+reportColumns+` FROM reports WHERE id = $1`, reportID))
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			err = ErrReportNotFound
+			return models.Report{}, err
+		case err != nil:
+			return models.Report{}, err
+		case report.Status == models.ReportClaimed && report.AssignedTo != nil && *report.AssignedTo == moderatorID:
+			err = tx.Commit(ctx)
+			return report, err
+		default:
+			err = ErrReportUnavailable
+			return models.Report{}, err
+		}
+	}
+	if err != nil {
+		return models.Report{}, err
+	}
+
+	if err = logModeratorAction(ctx, tx, moderatorID, "claim", &report, nil); err != nil {
+		return models.Report{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return models.Report{}, err
+	}
+
+	return report, nil
+}
+
+// ResolveReport applies the moderator's decision to the reported target and closes the report.
+// Other open reports on the same target are closed with it unless the report is dismissed
+func (m *ModerationRepository) ResolveReport(ctx context.Context, moderatorID, reportID string, body models.ResolveReport) (models.Report, error) {
+	// Begin transaction
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return models.Report{}, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Println("failed to rollback transaction:", rollbackErr)
+			}
+		}
+	}()
+
+	report, err := scanReport(tx.QueryRow(ctx, `SELECT `+reportColumns+` FROM reports WHERE id = $1 FOR UPDATE`, reportID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrReportNotFound
+		}
+		return models.Report{}, err
+	}
+
+	switch {
+	case report.Status == models.ReportOpen:
+		err = ErrReportNotClaimed
+		return models.Report{}, err
+	case report.Status == models.ReportResolved, report.AssignedTo == nil || *report.AssignedTo != moderatorID:
+		err = ErrReportUnavailable
+		return models.Report{}, err
+	}
+
+	if report.TargetType == models.ReportUser && (body.Action == models.ResolveHide || body.Action == models.ResolveDelete || body.Action == models.ResolveRestore) {
+		err = ErrInvalidResolution
+		return models.Report{}, err
+	}
+
+	details := map[string]any{"action": body.Action}
+	if body.Note != "" {
+		details["note"] = body.Note
+	}
+
+	var note *string
+	if body.Note != "" {
+		note = &body.Note
+	}
+	resolveQuery := `
+		UPDATE reports
+		SET status = 'resolved', resolution = $2, resolution_note = $3, resolved_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING ` + reportColumns
+	if report, err = scanReport(tx.QueryRow(ctx, resolveQuery, reportID, body.Action, note)); err != nil {
+		return models.Report{}, err
+	}
+
+	// Duplicates are matched before a delete clears the target ids
+	if body.Action != models.ResolveDismiss {
+		duplicatesQuery := `
+			UPDATE reports
+			SET status = 'resolved',
+				assigned_to = COALESCE(assigned_to, $2),
+				resolution = $3,
+				resolution_note = $4,
+				resolved_at = CURRENT_TIMESTAMP
+			WHERE id <> $1
+				AND status <> 'resolved'
+				AND target_type = $5
+				AND target_user_id = $6
+				AND post_id IS NOT DISTINCT FROM $7
+				AND comment_id IS NOT DISTINCT FROM $8
+		`
+		tag, execErr := tx.Exec(ctx, duplicatesQuery,
+			reportID, moderatorID, body.Action, note, report.TargetType, report.TargetUserID, report.PostID, report.CommentID,
+		)
+		if err = execErr; err != nil {
+			return models.Report{}, err
+		}
+		details["closed_duplicates"] = tag.RowsAffected()
+	}
+
+	var suspendedUntil time.Time
+	switch body.Action {
+	case models.ResolveHide, models.ResolveDelete, models.ResolveRestore:
+		if err = applyContentAction(ctx, tx, body.Action, report); err != nil {
+			return models.Report{}, err
+		}
+
+	case models.ResolveSuspend:
+		days := body.SuspendDays
+		if days == 0 {
+			days = defaultSuspendDays
+		}
+		suspendedUntil = time.Now().AddDate(0, 0, days)
+		details["suspended_until"] = suspendedUntil
+
+		// A ban outweighs a suspension
+		suspendQuery := `
+			UPDATE users
+			SET status = 'suspended', suspended_until = $2
+			WHERE id = $1 AND status <> 'banned'
+		`
+		if _, err = tx.Exec(ctx, suspendQuery, report.TargetUserID, suspendedUntil); err != nil {
+			return models.Report{}, err
+		}
+	}
+
+	if err = logModeratorAction(ctx, tx, moderatorID, "resolve", &report, details); err != nil {
+		return models.Report{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return models.Report{}, err
+	}
+
+	switch body.Action {
+	case models.ResolveHide, models.ResolveDelete, models.ResolveRestore:
+		// The author and their followers may have the post in a cached feed, and the post cache holds its comments
+		m.pr.InvalidateAuthorFeedCaches(ctx, report.TargetUserID)
+		m.pr.InvalidateTimelineCache(ctx, report.TargetUserID)
+		if report.PostID != nil {
+			m.pr.InvalidatePostCache(ctx, *report.PostID)
+		}
+
+		// Held or hidden comments did not count towards trending, a restored one does now.
+		// The vote marker keeps a comment that was already counted from counting twice
+		if body.Action == models.ResolveRestore && report.TargetType == models.ReportComment && report.CommentID != nil && report.PostID != nil {
+			m.pr.recordTrendingEvent(ctx, report.TargetUserID, *report.PostID, trendingComment)
+		}
+	case models.ResolveSuspend:
+		if err := m.ac.BlacklistUserTokens(ctx, report.TargetUserID, time.Now(), pkg.TokenLifetime); err != nil {
+			log.Printf("Failed to revoke tokens of suspended user %s: %v", report.TargetUserID, err)
+		}
+	}
+
+	return report, nil
+}
+
+// applyContentAction hides, restores or deletes the reported post or comment.
+// Files of deleted posts are left to the cleanup-media command
+func applyContentAction(ctx context.Context, tx pgx.Tx, action string, report models.Report) error {
+	table, id := "posts", report.PostID
+	if report.TargetType == models.ReportComment {
+		table, id = "post_comments", report.CommentID
+	}
+
+	// Already deleted by its author, nothing left to act on
+	if id == nil {
+		return nil
+	}
+
+	query := `UPDATE ` + table + ` SET hidden_at = CURRENT_TIMESTAMP WHERE id = $1 AND hidden_at IS NULL`
+	switch action {
+	case models.ResolveDelete:
+		query = `DELETE FROM ` + table + ` WHERE id = $1`
+	case models.ResolveRestore:
+		query = `UPDATE ` + table + ` SET hidden_at = NULL WHERE id = $1`
+	}
+
+	_, err := tx.Exec(ctx, query, *id)
+	return err
+}
+
+// logModeratorAction appends an entry about a report to the moderation audit log
+func logModeratorAction(ctx context.Context, tx pgx.Tx, moderatorID, action string, report *models.Report, details map[string]any) error {
+	targetID := &report.TargetUserID
+	switch report.TargetType {
+	case models.ReportPost:
+		targetID = report.PostID
+	case models.ReportComment:
+		targetID = report.CommentID
+	}
+
+	return appendAuditLog(ctx, tx, moderatorID, action, &report.ID, report.TargetType, targetID, details)
+}
+
+// appendAuditLog writes one moderation_audit_log row, the table rejects updates and deletes
+func appendAuditLog(ctx context.Context, tx pgx.Tx, moderatorID, action string, reportID *string, targetType string, targetID *string, details map[string]any) error {
+	if details == nil {
+		details = map[string]any{}
+	}
+
+	query := `
+		INSERT INTO moderation_audit_log (moderator_id, action, report_id, target_type, target_id, details)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := tx.Exec(ctx, query, moderatorID, action, reportID, targetType, targetID, details)
+	return err
+}
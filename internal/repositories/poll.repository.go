@@ -0,0 +1,180 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/radifan9/social-media-backend/internal/models"
+)
+
+var (
+	ErrPollNotFound       = errors.New("post has no poll")
+	ErrPollClosed         = errors.New("poll is closed")
+	ErrPollOptionNotFound = errors.New("poll option not found")
+	ErrAlreadyVoted       = errors.New("already voted in this poll")
+)
+
+// insertPoll attaches poll to postID inside tx
+func insertPoll(ctx context.Context, tx pgx.Tx, postID string, poll models.NewPoll) (*models.Poll, error) {
+	result := &models.Poll{Options: make([]models.PollOption, 0, len(poll.Options))}
+
+	if err := tx.QueryRow(ctx, `INSERT INTO polls (post_id, closes_at) VALUES ($1, $2) RETURNING closes_at`, postID, poll.ClosesAt).Scan(&result.ClosesAt); err != nil {
+		return nil, err
+	}
+
+	for i, label := range poll.Options {
+		query := `
+			INSERT INTO poll_options (post_id, position, label)
+			VALUES ($1, $2, $3)
+			RETURNING id, position, label
+		`
+
+		var option models.PollOption
+		if err := tx.QueryRow(ctx, query, postID, i, label).Scan(&option.ID, &option.Position, &option.Label); err != nil {
+			return nil, err
+		}
+		result.Options = append(result.Options, option)
+	}
+
+	return result, nil
+}
+
+// feedPollSQL is the poll of a post with the vote count of every option, or NULL
+func feedPollSQL(postID string) string {
+	return fmt.Sprintf(`(
+		SELECT JSONB_BUILD_OBJECT(
+			'closes_at', pl.closes_at,
+			'options', (
+				SELECT JSON_AGG(
+					JSONB_BUILD_OBJECT(
+						'id', po.id,
+						'position', po.position,
+						'label', po.label,
+						'votes', (SELECT COUNT(*) FROM poll_votes pv WHERE pv.option_id = po.id)
+					) ORDER BY po.position
+				)
+				FROM poll_options po
+				WHERE po.post_id = pl.post_id
+			)
+		)
+		FROM polls pl
+		WHERE pl.post_id = %s
+	)`, postID)
+}
+
+// viewerPoll is poll as seen by a viewer who voted for vote (nil when they did not vote).
+// Results stay hidden until the viewer voted or the poll closed
+func viewerPoll(poll *models.Poll, vote *string, now time.Time) *models.Poll {
+	if poll == nil {
+		return nil
+	}
+
+	// Copy, the options of the shared post must not change
+	seen := *poll
+	seen.Options = make([]models.PollOption, len(poll.Options))
+	seen.Closed = !now.Before(poll.ClosesAt)
+	seen.ViewerVote = vote
+
+	if vote == nil && !seen.Closed {
+		for i, o := range poll.Options {
+			seen.Options[i] = models.PollOption{ID: o.ID, Position: o.Position, Label: o.Label}
+		}
+		seen.TotalVotes = nil
+		return &seen
+	}
+
+	total := 0
+	for _, o := range poll.Options {
+		if o.Votes != nil {
+			total += *o.Votes
+		}
+	}
+	seen.TotalVotes = &total
+
+	for i, o := range poll.Options {
+		votes := 0
+		if o.Votes != nil {
+			votes = *o.Votes
+		}
+
+		// Percentages are rounded to one decimal
+		percentage := 0.0
+		if total > 0 {
+			percentage = math.Round(float64(votes)*1000/float64(total)) / 10
+		}
+		seen.Options[i] = models.PollOption{ID: o.ID, Position: o.Position, Label: o.Label, Votes: &votes, Percentage: &percentage}
+	}
+
+	return &seen
+}
+
+// VotePoll records the vote of userID for optionID in the poll of postID and returns
+// the poll with its results. Every user votes once, the primary key of poll_votes
+// settles concurrent votes of the same user and counts are read from the votes themselves
+func (p *PostRepository) VotePoll(ctx context.Context, userID, postID, optionID string) (*models.Poll, error) {
+	visible, err := p.canInteract(ctx, userID, postID)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, ErrPostNotFound
+	}
+
+	query := `
+		INSERT INTO poll_votes (post_id, user_id, option_id)
+		SELECT po.post_id, $3::uuid, po.id
+		FROM poll_options po
+		INNER JOIN polls pl ON po.post_id = pl.post_id
+		WHERE po.post_id = $1 AND po.id = $2 AND pl.closes_at > CURRENT_TIMESTAMP
+		ON CONFLICT (post_id, user_id) DO NOTHING
+	`
+	tag, err := p.db.Exec(ctx, query, postID, optionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing inserted, find out why
+	if tag.RowsAffected() == 0 {
+		var closed, voted bool
+		reasonQuery := `
+			SELECT
+				pl.closes_at <= CURRENT_TIMESTAMP,
+				EXISTS(SELECT 1 FROM poll_votes pv WHERE pv.post_id = pl.post_id AND pv.user_id = $2)
+			FROM polls pl
+			WHERE pl.post_id = $1
+		`
+		if err := p.db.QueryRow(ctx, reasonQuery, postID, userID).Scan(&closed, &voted); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrPollNotFound
+			}
+			return nil, err
+		}
+
+		switch {
+		case voted:
+			return nil, ErrAlreadyVoted
+		case closed:
+			return nil, ErrPollClosed
+		default:
+			// The option is not one of this poll
+			return nil, ErrPollOptionNotFound
+		}
+	}
+
+	// The vote counts changed
+	p.InvalidatePostCache(ctx, postID)
+
+	posts, err := p.GetFeedPostsByIDs(ctx, userID, []string{postID})
+	if err != nil {
+		return nil, err
+	}
+	if len(posts) == 0 || posts[0].Poll == nil {
+		return nil, ErrPostNotFound
+	}
+
+	return posts[0].Poll, nil
+}
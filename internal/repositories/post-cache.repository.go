@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/radifan9/social-media-backend/internal/models"
+)
+
+// How long the shared part of a post stays cached. Likes, comments, reposts and moderation
+// invalidate it right away, profile changes of the author show up once it expires
+const postCacheTTL = 5 * time.Minute
+
+func postCacheKey(postID string) string {
+	return fmt.Sprintf("sosmed:post:%s", postID)
+}
+
+// getSharedPosts returns the shared part of the given posts, reading what it can from the cache
+// and loading and caching the rest. Posts that are gone or hidden are missing from the map
+func (p *PostRepository) getSharedPosts(ctx context.Context, postIDs []string) (map[string]models.FeedPost, error) {
+	byID := make(map[string]models.FeedPost, len(postIDs))
+
+	keys := make([]string, len(postIDs))
+	for i, id := range postIDs {
+		keys[i] = postCacheKey(id)
+	}
+
+	var missing []string
+	cached, err := p.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		log.Printf("Failed to read post cache: %v", err)
+		missing = postIDs
+	} else {
+		for i, v := range cached {
+			raw, ok := v.(string)
+			if !ok {
+				missing = append(missing, postIDs[i])
+				continue
+			}
+
+			var post models.FeedPost
+			if err := json.Unmarshal([]byte(raw), &post); err != nil {
+				log.Printf("Failed to decode cached post %s: %v", postIDs[i], err)
+				missing = append(missing, postIDs[i])
+				continue
+			}
+			byID[post.PostID] = post
+		}
+	}
+
+	if len(missing) == 0 {
+		return byID, nil
+	}
+
+	posts, err := p.querySharedPosts(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := p.rdb.Pipeline()
+	for _, post := range posts {
+		byID[post.PostID] = post
+
+		bt, err := json.Marshal(post)
+		if err != nil {
+			log.Printf("Failed to encode post %s for cache: %v", post.PostID, err)
+			continue
+		}
+		pipe.Set(ctx, postCacheKey(post.PostID), bt, postCacheTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to write post cache: %v", err)
+	}
+
+	return byID, nil
+}
+
+// InvalidatePostCache drops the shared data of the given posts so the next read reloads it
+func (p *PostRepository) InvalidatePostCache(ctx context.Context, postIDs ...string) {
+	if len(postIDs) == 0 {
+		return
+	}
+
+	keys := make([]string, len(postIDs))
+	for i, id := range postIDs {
+		keys[i] = postCacheKey(id)
+	}
+	if err := p.rdb.Del(ctx, keys...).Err(); err != nil {
+		log.Printf("Failed to invalidate post cache for %v: %v", postIDs, err)
+	}
+}
@@ -2,15 +2,23 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/radifan9/social-media-backend/pkg"
 	"github.com/redis/go-redis/v9"
 )
 
+// Number of posts per page for cursor-paginated lists
+const pageSize = 20
+
 type PostRepository struct {
 	db           *pgxpool.Pool
 	rdb          *redis.Client
@@ -25,7 +33,20 @@ func NewPostRepository(db *pgxpool.Pool, rdb *redis.Client) *PostRepository {
 	}
 }
 
-func (p *PostRepository) CreatePost(ctx context.Context, userID string, body models.CreatePost, imagePaths []string) (models.Post, error) {
+// CreatePost stores a post with its media. A non-nil holdReason stores it hidden
+// and files a content filter report for moderators
+func (p *PostRepository) CreatePost(ctx context.Context, userID string, body models.CreatePost, media []models.PostImage, holdReason *string) (models.Post, error) {
+	// A quoted post has to be visible to the author of the quote
+	if body.QuotedPostID != nil {
+		visible, err := p.canInteract(ctx, userID, *body.QuotedPostID)
+		if err != nil {
+			return models.Post{}, err
+		}
+		if !visible {
+			return models.Post{}, ErrPostNotFound
+		}
+	}
+
 	// Begin transaction
 	tx, err := p.db.Begin(ctx)
 	if err != nil {
@@ -39,141 +60,550 @@ func (p *PostRepository) CreatePost(ctx context.Context, userID string, body mod
 		}
 	}()
 
+	post, err := p.insertPost(ctx, tx, userID, body, media, holdReason)
+	if err != nil {
+		return models.Post{}, err
+	}
+
+	// Commit
+	if err = tx.Commit(ctx); err != nil {
+		return models.Post{}, err
+	}
+
+	p.afterPublish(ctx, post)
+
+	return post, nil
+}
+
+// insertPost writes a post, its media and entities inside tx. Mentions notify their users
+// unless the post is held, held posts are also queued for moderation
+func (p *PostRepository) insertPost(ctx context.Context, tx pgx.Tx, userID string, body models.CreatePost, media []models.PostImage, holdReason *string) (models.Post, error) {
 	// Step  1 : Insert into posts table
+	visibility := body.Visibility
+	if visibility == "" {
+		visibility = models.VisibilityPublic
+	}
+
 	var post models.Post
 	postQuery := `
-		Insert into posts (user_id, text_content)
-		values ($1, $2)
-		returning id, user_id, text_content, created_at
+		Insert into posts (user_id, text_content, hidden_at, quoted_post_id, visibility)
+		values ($1, $2, CASE WHEN $3 THEN CURRENT_TIMESTAMP END, $4, $5)
+		returning id, user_id, text_content, visibility, created_at, hidden_at IS NOT NULL, quoted_post_id
 	`
 
-	if err = tx.QueryRow(ctx, postQuery, userID, body.TextContent).Scan(
-		&post.ID, &post.UserID, &post.TextContent, &post.CreatedAt,
+	if err := tx.QueryRow(ctx, postQuery, userID, body.TextContent, holdReason != nil, body.QuotedPostID, visibility).Scan(
+		&post.ID, &post.UserID, &post.TextContent, &post.Visibility, &post.CreatedAt, &post.HeldForReview, &post.QuotedPostID,
 	); err != nil {
 		return models.Post{}, err
 	}
 
-	// Step 2 : Insert images (if any)
-	for _, path := range imagePaths {
+	// Step 2 : Insert media (if any)
+	// Images are ready right away, gifs and videos wait for the media worker
+	for i, m := range media {
+		status := "ready"
+		if m.MediaType != pkg.MediaImage {
+			status = "pending"
+		}
+
 		imgQuery := `
-			Insert into post_images (post_id, image_url)
-			values ($1, $2)
-			returning id, post_id, image_url, created_at 	
+			Insert into post_images (post_id, media_url, media_type, status, size_bytes, position, alt_text)
+			values ($1, $2, $3, $4, $5, $6, $7)
+			returning id, post_id, media_url, media_type, status, size_bytes, position, alt_text, created_at
 		`
 
 		var img models.PostImage
-		if err = tx.QueryRow(ctx, imgQuery, post.ID, path).Scan(
-			&img.ID, &img.PostID, &img.ImageURL, &img.CreatedAt,
+		if err := tx.QueryRow(ctx, imgQuery, post.ID, m.MediaURL, m.MediaType, status, m.SizeBytes, i, m.AltText).Scan(
+			&img.ID, &img.PostID, &img.MediaURL, &img.MediaType, &img.Status, &img.SizeBytes, &img.Position, &img.AltText, &img.CreatedAt,
 		); err != nil {
 			return models.Post{}, err
 		}
-		post.Images = append(post.Images, img.ImageURL)
+		post.Media = append(post.Media, img)
 	}
 
-	// Commit
-	if err = tx.Commit(ctx); err != nil {
+	// Step 3 : Poll (if any)
+	if body.Poll != nil {
+		var err error
+		if post.Poll, err = insertPoll(ctx, tx, post.ID, *body.Poll); err != nil {
+			return models.Post{}, err
+		}
+	}
+
+	// Step 4 : Hashtags and mentions in the text
+	// Mentions in held posts do not notify anyone
+	var err error
+	if post.Entities, err = p.saveEntities(ctx, tx, userID, post.ID, nil, body.TextContent, holdReason == nil); err != nil {
 		return models.Post{}, err
 	}
 
+	// Step 5 : Queue held posts for moderation
+	if holdReason != nil {
+		if err := fileFilterReport(ctx, tx, models.ReportPost, userID, post.ID, nil, postSnapshot(body), *holdReason); err != nil {
+			return models.Post{}, err
+		}
+	}
+
 	return post, nil
 }
 
+// postSnapshot is the content of a post as moderators see it in a report. Poll options go
+// through the content filter too, so they are listed after the text
+func postSnapshot(body models.CreatePost) string {
+	if body.Poll == nil {
+		return body.TextContent
+	}
+
+	var b strings.Builder
+	b.WriteString(body.TextContent)
+	if body.TextContent != "" {
+		b.WriteString("\n\n")
+	}
+	b.WriteString("Poll options:")
+	for _, option := range body.Poll.Options {
+		b.WriteString("\n- ")
+		b.WriteString(option)
+	}
+	return b.String()
+}
+
+// afterPublish drops the caches a newly published post shows up in
+func (p *PostRepository) afterPublish(ctx context.Context, post models.Post) {
+	// Held posts stay out of every feed until a moderator restores them
+	if !post.HeldForReview {
+		p.InvalidateAuthorFeedCaches(ctx, post.UserID)
+	}
+	p.InvalidateTimelineCache(ctx, post.UserID)
+
+	// The quote count of the quoted post changed
+	if post.QuotedPostID != nil {
+		p.InvalidatePostCache(ctx, *post.QuotedPostID)
+	}
+}
+
+// feedEntry is what the feed cache keeps for each post, the post itself comes from the post cache
+type feedEntry struct {
+	PostID     string           `json:"post_id"`
+	RepostedBy *models.Reposter `json:"reposted_by,omitempty"`
+}
+
 func (p *PostRepository) GetFollowingFeed(ctx context.Context, userID string) ([]models.FeedPost, error) {
 	// Create cache key for this user's feed
-	cacheKey := fmt.Sprintf("sosmed:feed:%s", userID)
+	cacheKey := feedCacheKey(userID)
 
 	// Try to get from cache first
-	var cachedPosts []models.FeedPost
-	if p.cacheManager.GetFromCache(ctx, cacheKey, &cachedPosts) {
+	var entries []feedEntry
+	if p.cacheManager.GetFromCache(ctx, cacheKey, &entries) {
 		log.Printf("Feed cache hit for user: %s", userID)
-		return cachedPosts, nil
+	} else {
+		var err error
+		if entries, err = p.queryFeedEntries(ctx, userID); err != nil {
+			return []models.FeedPost{}, err
+		}
+
+		// Cache the result for 5 minutes
+		p.cacheManager.SetCache(ctx, cacheKey, entries, 5*time.Minute)
+		log.Printf("Feed cached for user: %s", userID)
+	}
+
+	postIDs := make([]string, 0, len(entries))
+	repostedBy := make(map[string]*models.Reposter)
+	for _, e := range entries {
+		postIDs = append(postIDs, e.PostID)
+		if e.RepostedBy != nil {
+			repostedBy[e.PostID] = e.RepostedBy
+		}
+	}
+
+	posts, err := p.GetFeedPostsByIDs(ctx, userID, postIDs)
+	if err != nil {
+		return []models.FeedPost{}, err
+	}
+	for i := range posts {
+		posts[i].RepostedBy = repostedBy[posts[i].PostID]
+	}
+
+	return posts, nil
+}
+
+// queryFeedEntries picks the posts for the feed of userID
+func (p *PostRepository) queryFeedEntries(ctx context.Context, userID string) ([]feedEntry, error) {
+	// The feed has the user's own posts and those of followed users.
+	// Reposts are replaced by the post they point at, and a post shows up once,
+	// at its most recent appearance, however many followed users reposted it
+	query := `
+		SELECT f.post_id, f.reposter_id, rup.name, f.created_at
+		FROM (
+			SELECT DISTINCT ON (COALESCE(p.reposted_post_id, p.id))
+				COALESCE(p.reposted_post_id, p.id) as post_id,
+				CASE WHEN p.reposted_post_id IS NOT NULL THEN p.user_id END as reposter_id,
+				p.created_at
+			FROM posts p
+			LEFT JOIN posts o ON o.id = p.reposted_post_id
+			WHERE (
+					p.user_id = $1
+					OR EXISTS (SELECT 1 FROM user_followers uf WHERE uf.user_id = p.user_id AND uf.follower_id = $1)
+				)
+				AND p.hidden_at IS NULL
+				AND ` + notDeletedSQL("p.user_id") + `
+				AND ` + notMutedSQL("$1", "p.user_id") + `
+				AND ` + postAudienceSQL("$1", "p") + `
+				AND (o.id IS NULL OR (
+					o.hidden_at IS NULL
+					AND ` + notMutedSQL("$1", "o.user_id") + `
+					AND ` + canViewAuthorSQL("$1", "o.user_id") + `
+					AND ` + postAudienceSQL("$1", "o") + `
+				))
+			ORDER BY COALESCE(p.reposted_post_id, p.id), p.created_at DESC
+		) f
+		LEFT JOIN user_profiles rup ON f.reposter_id = rup.user_id
+		ORDER BY f.created_at DESC
+		LIMIT 10
+	`
+
+	rows, err := p.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []feedEntry{}
+	for rows.Next() {
+		var entry feedEntry
+		var reposterID, reposterName *string
+		var createdAt time.Time
+		if err := rows.Scan(&entry.PostID, &reposterID, &reposterName, &createdAt); err != nil {
+			return nil, err
+		}
+		if reposterID != nil {
+			entry.RepostedBy = &models.Reposter{UserID: *reposterID, Name: reposterName, RepostedAt: createdAt}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetFeedPostsByIDs builds the feed representation of the given posts as seen by viewerID,
+// keeping the order of postIDs. The shared part of each post comes from the post cache,
+// the viewer's overlay (likes, bookmarks, blocks) is read fresh on every call
+func (p *PostRepository) GetFeedPostsByIDs(ctx context.Context, viewerID string, postIDs []string) ([]models.FeedPost, error) {
+	if len(postIDs) == 0 {
+		return []models.FeedPost{}, nil
+	}
+
+	byID, err := p.getSharedPosts(ctx, postIDs)
+	if err != nil {
+		return []models.FeedPost{}, err
+	}
+
+	overlays, err := p.getViewerOverlays(ctx, viewerID, postIDs)
+	if err != nil {
+		return []models.FeedPost{}, err
 	}
 
+	now := time.Now()
+	posts := make([]models.FeedPost, 0, len(postIDs))
+	for _, id := range postIDs {
+		post, ok := byID[id]
+		if !ok {
+			continue
+		}
+		overlay := overlays[id]
+
+		post.ViewerHasLiked = overlay.liked
+		post.ViewerHasCommented = overlay.commented
+		post.ViewerFollowsAuthor = overlay.followsAuthor
+		post.Bookmarked = overlay.bookmarked
+		post.Poll = viewerPoll(post.Poll, overlay.pollVote, now)
+
+		// Comments from users in a block with the viewer are left out
+		if len(overlay.blockedCommenters) > 0 {
+			comments := make([]models.FeedComment, 0, len(post.Comments))
+			for _, c := range post.Comments {
+				if !slices.Contains(overlay.blockedCommenters, c.UserID) {
+					comments = append(comments, c)
+				}
+			}
+			post.Comments = comments
+		}
+
+		if post.QuotedPost != nil && !overlay.quotedVisible {
+			post.QuotedPost = &models.QuotedPost{PostID: post.QuotedPost.PostID, Unavailable: true}
+		}
+
+		posts = append(posts, post)
+	}
+
+	return posts, nil
+}
+
+// querySharedPosts loads the part of the given posts that is the same for every viewer.
+// Reposts, hidden posts and hidden comments are left out
+func (p *PostRepository) querySharedPosts(ctx context.Context, postIDs []string) ([]models.FeedPost, error) {
 	query := `
 		SELECT 
 			p.id,
 			p.user_id,
-			p.text_content,
+			COALESCE(p.text_content, ''),
+			p.visibility,
 			p.created_at,
 			up.name as author_name,
-			COUNT(DISTINCT pl.id) as like_count,
-			ARRAY_AGG(DISTINCT pi.image_url) FILTER (WHERE pi.image_url IS NOT NULL) as images,
-			JSON_AGG(
-				JSONB_BUILD_OBJECT(
-					'name', COALESCE(cup.name, cu.email),
-					'comment_text', pc.comment,
-					'created_at', pc.created_at
-				) ORDER BY pc.created_at DESC
-			) FILTER (WHERE pc.id IS NOT NULL) as comments
+			up.username as author_username,
+			up.avatar as author_avatar,
+			(SELECT COUNT(*) FROM post_likes pl WHERE pl.post_id = p.id) as like_count,
+			(
+				SELECT COUNT(*) FROM posts rp
+				WHERE rp.reposted_post_id = p.id AND ` + notDeletedSQL("rp.user_id") + `
+			) as repost_count,
+			(
+				SELECT COUNT(*) FROM posts qp
+				WHERE qp.quoted_post_id = p.id AND qp.hidden_at IS NULL AND ` + notDeletedSQL("qp.user_id") + `
+			) as quote_count,
+			` + feedMediaSQL("p.id") + ` as media,
+			COALESCE((
+				SELECT JSON_AGG(
+					JSONB_BUILD_OBJECT(
+						'user_id', pc.user_id,
+						'name', COALESCE(cup.name, cu.email),
+						'comment_text', pc.comment,
+						'created_at', pc.created_at
+					) ORDER BY pc.created_at DESC
+				)
+				FROM post_comments pc
+				INNER JOIN users cu ON pc.user_id = cu.id
+				LEFT JOIN user_profiles cup ON pc.user_id = cup.user_id
+				WHERE pc.post_id = p.id
+					AND pc.hidden_at IS NULL
+					AND ` + notDeletedSQL("pc.user_id") + `
+			), '[]') as comments,
+			COALESCE((
+				SELECT JSON_AGG(e.entity ORDER BY e.start_index)
+				FROM (
+					SELECT
+						JSONB_BUILD_OBJECT('type', 'hashtag', 'text', h.tag, 'start', ph.start_index, 'end', ph.end_index) as entity,
+						ph.start_index
+					FROM post_hashtags ph
+					INNER JOIN hashtags h ON ph.hashtag_id = h.id
+					WHERE ph.post_id = p.id AND ph.comment_id IS NULL
+					UNION ALL
+					SELECT
+						JSONB_BUILD_OBJECT('type', 'mention', 'text', m.handle, 'user_id', m.user_id, 'start', m.start_index, 'end', m.end_index),
+						m.start_index
+					FROM mentions m
+					WHERE m.post_id = p.id AND m.comment_id IS NULL
+				) e
+			), '[]') as entities,
+			CASE WHEN p.quoted_post_id IS NOT NULL THEN COALESCE((
+				SELECT JSONB_BUILD_OBJECT(
+					'post_id', qp.id,
+					'user_id', qp.user_id,
+					'author_name', qup.name,
+					'text_content', COALESCE(qp.text_content, ''),
+					'created_at', qp.created_at,
+					'media', ` + feedMediaSQL("qp.id") + `
+				)
+				FROM posts qp
+				LEFT JOIN user_profiles qup ON qp.user_id = qup.user_id
+				WHERE qp.id = p.quoted_post_id
+					AND qp.hidden_at IS NULL
+					AND ` + notDeletedSQL("qp.user_id") + `
+			), JSONB_BUILD_OBJECT('post_id', p.quoted_post_id, 'unavailable', true)) END as quoted_post,
+			` + feedPollSQL("p.id") + ` as poll
 		FROM posts p
-		INNER JOIN user_followers uf ON p.user_id = uf.user_id
-		INNER JOIN users u ON p.user_id = u.id
 		LEFT JOIN user_profiles up ON p.user_id = up.user_id
-		LEFT JOIN post_likes pl ON p.id = pl.post_id
-		LEFT JOIN post_comments pc ON p.id = pc.post_id
-		LEFT JOIN users cu ON pc.user_id = cu.id
-		LEFT JOIN user_profiles cup ON pc.user_id = cup.user_id
-		LEFT JOIN post_images pi ON p.id = pi.post_id
-		WHERE uf.follower_id = $1
-		GROUP BY p.id, p.user_id, p.text_content, p.created_at, u.email, up.name, up.avatar
-		ORDER BY p.created_at DESC
-		LIMIT 10
+		WHERE p.id = ANY($1) AND p.hidden_at IS NULL AND p.reposted_post_id IS NULL
 	`
 
-	rows, err := p.db.Query(ctx, query, userID)
+	rows, err := p.db.Query(ctx, query, postIDs)
 	if err != nil {
-		return []models.FeedPost{}, err
+		return nil, err
 	}
 	defer rows.Close()
 
 	var posts []models.FeedPost
-
 	for rows.Next() {
 		var post models.FeedPost
-		var comments []models.FeedComment
 
 		if err := rows.Scan(
 			&post.PostID,
 			&post.UserID,
 			&post.TextContent,
+			&post.Visibility,
 			&post.CreatedAt,
 			&post.AuthorName,
+			&post.AuthorUsername,
+			&post.AuthorAvatar,
 			&post.LikeCount,
-			&post.Images,
-			&comments,
+			&post.RepostCount,
+			&post.QuoteCount,
+			&post.Media,
+			&post.Comments,
+			&post.Entities,
+			&post.QuotedPost,
+			&post.Poll,
 		); err != nil {
-			return []models.FeedPost{}, err
+			return nil, err
 		}
 
-		if comments != nil {
-			post.Comments = []models.FeedComment(comments)
-		} else {
-			post.Comments = []models.FeedComment{}
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
+}
+
+// viewerOverlay is the part of a feed post that depends on who is looking at it
+type viewerOverlay struct {
+	liked             bool
+	commented         bool
+	bookmarked        bool
+	followsAuthor     bool
+	quotedVisible     bool
+	blockedCommenters []string
+	pollVote          *string
+}
+
+// getViewerOverlays reads the per-viewer state of the given posts
+func (p *PostRepository) getViewerOverlays(ctx context.Context, viewerID string, postIDs []string) (map[string]viewerOverlay, error) {
+	query := `
+		SELECT
+			p.id,
+			EXISTS(SELECT 1 FROM post_likes pl WHERE pl.post_id = p.id AND pl.user_id = $2),
+			EXISTS(SELECT 1 FROM post_comments pc WHERE pc.post_id = p.id AND pc.user_id = $2),
+			EXISTS(SELECT 1 FROM bookmarks b WHERE b.post_id = p.id AND b.user_id = $2),
+			EXISTS(SELECT 1 FROM user_followers uf WHERE uf.user_id = p.user_id AND uf.follower_id = $2),
+			p.quoted_post_id IS NULL OR EXISTS(
+				SELECT 1 FROM posts qp
+				WHERE qp.id = p.quoted_post_id
+					AND qp.hidden_at IS NULL
+					AND ` + canViewAuthorSQL("$2", "qp.user_id") + `
+					AND ` + postAudienceSQL("$2", "qp") + `
+			),
+			ARRAY(
+				SELECT DISTINCT pc.user_id::text
+				FROM post_comments pc
+				WHERE pc.post_id = p.id AND NOT ` + notBlockedSQL("$2", "pc.user_id") + `
+			),
+			(SELECT pv.option_id::text FROM poll_votes pv WHERE pv.post_id = p.id AND pv.user_id = $2)
+		FROM posts p
+		WHERE p.id = ANY($1)
+	`
+
+	rows, err := p.db.Query(ctx, query, postIDs, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overlays := make(map[string]viewerOverlay, len(postIDs))
+	for rows.Next() {
+		var id string
+		var o viewerOverlay
+		if err := rows.Scan(&id, &o.liked, &o.commented, &o.bookmarked, &o.followsAuthor, &o.quotedVisible, &o.blockedCommenters, &o.pollVote); err != nil {
+			return nil, err
 		}
+		overlays[id] = o
+	}
 
-		posts = append(posts, post)
+	return overlays, rows.Err()
+}
+
+// feedMediaSQL is a JSON array of the ready media of a post, in upload order
+func feedMediaSQL(postID string) string {
+	return fmt.Sprintf(`COALESCE((
+		SELECT JSON_AGG(
+			JSONB_BUILD_OBJECT(
+				'url', pi.media_url,
+				'media_type', pi.media_type,
+				'poster_url', pi.poster_url,
+				'duration_ms', pi.duration_ms,
+				'width', pi.width,
+				'height', pi.height,
+				'position', pi.position,
+				'alt_text', pi.alt_text
+			) ORDER BY pi.position
+		)
+		FROM post_images pi
+		WHERE pi.post_id = %s AND pi.status = 'ready'
+	), '[]')`, postID)
+}
+
+// queryPostPage runs a keyset query selecting (id, created_at) newest first with pageSize+1 rows
+// and turns it into a page of feed posts for viewerID
+func (p *PostRepository) queryPostPage(ctx context.Context, viewerID, query string, args ...any) (models.PostPage, error) {
+	postIDs, nextCursor, err := p.queryPostIDPage(ctx, query, args...)
+	if err != nil {
+		return models.PostPage{}, err
 	}
 
-	if err := rows.Err(); err != nil {
-		return []models.FeedPost{}, err
+	posts, err := p.GetFeedPostsByIDs(ctx, viewerID, postIDs)
+	if err != nil {
+		return models.PostPage{}, err
 	}
 
-	// Cache the result for 5 minutes
-	p.cacheManager.SetCache(ctx, cacheKey, posts, 5*time.Minute)
-	log.Printf("Feed cached for user: %s", userID)
+	return models.PostPage{Posts: posts, NextCursor: nextCursor}, nil
+}
 
-	return posts, nil
+// queryPostIDPage runs the keyset query of queryPostPage and returns the post ids of the page
+// with the cursor of the next one
+func (p *PostRepository) queryPostIDPage(ctx context.Context, query string, args ...any) ([]string, string, error) {
+	rows, err := p.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var postIDs []string
+	var lastCreatedAt time.Time
+	var nextCursor string
+	for rows.Next() {
+		var id string
+		var createdAt time.Time
+		if err := rows.Scan(&id, &createdAt); err != nil {
+			return nil, "", err
+		}
+
+		// The extra row only tells us another page exists
+		if len(postIDs) == pageSize {
+			nextCursor = pkg.EncodeCursor(lastCreatedAt, postIDs[len(postIDs)-1])
+			break
+		}
+		postIDs = append(postIDs, id)
+		lastCreatedAt = createdAt
+	}
+
+	return postIDs, nextCursor, rows.Err()
 }
 
-func (p *PostRepository) LikePost(ctx context.Context, userID, postID string) (models.LikeResponse, error) {
+var ErrPostNotFound = errors.New("post not found")
+
+// canInteract reports whether the post exists and userID may see it,
+// so blocked users and non-followers of private accounts get "post not found".
+// Reposts are not posts of their own and cannot be interacted with
+func (p *PostRepository) canInteract(ctx context.Context, userID, postID string) (bool, error) {
 	var exists bool
-	checkQuery := `SELECT EXISTS(SELECT 1 FROM posts WHERE id = $1)`
-	if err := p.db.QueryRow(ctx, checkQuery, postID).Scan(&exists); err != nil {
+	checkQuery := `
+		SELECT EXISTS(
+			SELECT 1 FROM posts p
+			WHERE p.id = $1
+				AND p.hidden_at IS NULL
+				AND p.reposted_post_id IS NULL
+				AND ` + canViewAuthorSQL("$2::uuid", "p.user_id") + `
+				AND ` + postAudienceSQL("$2::uuid", "p") + `
+		)
+	`
+	if err := p.db.QueryRow(ctx, checkQuery, postID, userID).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+func (p *PostRepository) LikePost(ctx context.Context, userID, postID string) (models.LikeResponse, error) {
+	exists, err := p.canInteract(ctx, userID, postID)
+	if err != nil {
 		return models.LikeResponse{}, err
 	}
 
 	if !exists {
-		return models.LikeResponse{}, fmt.Errorf("post not found")
+		return models.LikeResponse{}, ErrPostNotFound
 	}
 
 	// Try to insert the like
@@ -181,11 +611,11 @@ func (p *PostRepository) LikePost(ctx context.Context, userID, postID string) (m
 		INSERT INTO post_likes (post_id, user_id)
 		VALUES ($1, $2)
 		ON CONFLICT (post_id, user_id) DO NOTHING
-		RETURNING id, post_id, user_id, created_at
+		RETURNING post_id, user_id, created_at
 	`
 
 	var likeResp models.LikeResponse
-	err := p.db.QueryRow(ctx, query, postID, userID).Scan(
+	err = p.db.QueryRow(ctx, query, postID, userID).Scan(
 		&likeResp.PostID,
 		&likeResp.UserID,
 		&likeResp.CreatedAt,
@@ -204,46 +634,89 @@ func (p *PostRepository) LikePost(ctx context.Context, userID, postID string) (m
 
 	likeResp.Message = "post liked successfully"
 
-	// Invalidate feed cache for users who follow the post author
-	var postAuthorID string
-	authorQuery := `SELECT user_id FROM posts WHERE id = $1`
-	if err := p.db.QueryRow(ctx, authorQuery, postID).Scan(&postAuthorID); err == nil {
-		p.InvalidateFollowersFeedCache(ctx, postAuthorID)
-	}
+	// The like count is part of the shared post data
+	p.InvalidatePostCache(ctx, postID)
+	p.recordTrendingEvent(ctx, userID, postID, trendingLike)
 
 	return likeResp, nil
 }
 
-func (p *PostRepository) AddComment(ctx context.Context, userID, postID, comment string) (models.CommentResponse, error) {
+// AddComment stores a comment on a post userID can see. A non-nil holdReason stores it hidden
+// and files a content filter report for moderators
+func (p *PostRepository) AddComment(ctx context.Context, userID, postID, comment string, holdReason *string) (models.CommentResponse, error) {
+	exists, err := p.canInteract(ctx, userID, postID)
+	if err != nil {
+		return models.CommentResponse{}, err
+	}
+
+	if !exists {
+		return models.CommentResponse{}, ErrPostNotFound
+	}
+
+	// Begin transaction
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return models.CommentResponse{}, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Println("failed to rollback transaction:", rollbackErr)
+			}
+		}
+	}()
+
 	query := `
-		INSERT INTO post_comments (post_id, user_id, comment)
-		VALUES ($1, $2, $3)
-		RETURNING post_id, user_id, comment
+		INSERT INTO post_comments (post_id, user_id, comment, hidden_at)
+		VALUES ($1, $2, $3, CASE WHEN $4 THEN CURRENT_TIMESTAMP END)
+		RETURNING id, post_id, user_id, comment, created_at, hidden_at IS NOT NULL
 	`
 
 	var commentResp models.CommentResponse
-	err := p.db.QueryRow(ctx, query, postID, userID, comment).Scan(
+	if err = tx.QueryRow(ctx, query, postID, userID, comment, holdReason != nil).Scan(
+		&commentResp.ID,
 		&commentResp.PostID,
 		&commentResp.UserID,
 		&commentResp.Comment,
-	)
+		&commentResp.CreatedAt,
+		&commentResp.HeldForReview,
+	); err != nil {
+		return models.CommentResponse{}, err
+	}
 
-	if err != nil {
+	// Hashtags and mentions inside the comment
+	if commentResp.Entities, err = p.saveEntities(ctx, tx, userID, postID, &commentResp.ID, comment, holdReason == nil); err != nil {
 		return models.CommentResponse{}, err
 	}
 
-	// Invalidate feed cache for users who follow the post author
-	var postAuthorID string
-	authorQuery := `SELECT user_id FROM posts WHERE id = $1`
-	if err := p.db.QueryRow(ctx, authorQuery, postID).Scan(&postAuthorID); err == nil {
-		p.InvalidateFollowersFeedCache(ctx, postAuthorID)
+	if holdReason != nil {
+		if err = fileFilterReport(ctx, tx, models.ReportComment, userID, postID, &commentResp.ID, comment, *holdReason); err != nil {
+			return models.CommentResponse{}, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return models.CommentResponse{}, err
+	}
+
+	// Comments are part of the shared post data, and show up on the commenter's timeline with replies
+	p.InvalidatePostCache(ctx, postID)
+	p.InvalidateTimelineCache(ctx, userID)
+
+	// Held comments do not count until a moderator restores them
+	if !commentResp.HeldForReview {
+		p.recordTrendingEvent(ctx, userID, postID, trendingComment)
 	}
 
 	return commentResp, nil
 }
 
+func feedCacheKey(userID string) string {
+	return fmt.Sprintf("sosmed:feed:%s", userID)
+}
+
 func (p *PostRepository) InvalidateUserFeedCache(ctx context.Context, userID string) {
-	cacheKey := fmt.Sprintf("sosmed:feed:%s", userID)
+	cacheKey := feedCacheKey(userID)
 	if err := p.rdb.Del(ctx, cacheKey).Err(); err != nil {
 		log.Printf("Failed to invalidate feed cache for user %s: %v", userID, err)
 	} else {
@@ -251,6 +724,13 @@ func (p *PostRepository) InvalidateUserFeedCache(ctx context.Context, userID str
 	}
 }
 
+// InvalidateAuthorFeedCaches drops the cached feeds a post of userID shows up in:
+// the author's own feed and the feeds of their followers
+func (p *PostRepository) InvalidateAuthorFeedCaches(ctx context.Context, userID string) {
+	p.InvalidateUserFeedCache(ctx, userID)
+	p.InvalidateFollowersFeedCache(ctx, userID)
+}
+
 func (p *PostRepository) InvalidateFollowersFeedCache(ctx context.Context, userID string) {
 	// Get all followers of this user
 	query := `SELECT follower_id FROM user_followers WHERE user_id = $1`
@@ -278,3 +758,24 @@ func (p *PostRepository) InvalidateFollowersFeedCache(ctx context.Context, userI
 
 	log.Printf("Invalidated feed cache for %d followers of user %s", len(followerIDs), userID)
 }
+
+// GetPostDetail returns a single post if viewerID is allowed to see it
+func (p *PostRepository) GetPostDetail(ctx context.Context, viewerID, postID string) (models.FeedPost, error) {
+	visible, err := p.canInteract(ctx, viewerID, postID)
+	if err != nil {
+		return models.FeedPost{}, err
+	}
+	if !visible {
+		return models.FeedPost{}, ErrPostNotFound
+	}
+
+	posts, err := p.GetFeedPostsByIDs(ctx, viewerID, []string{postID})
+	if err != nil {
+		return models.FeedPost{}, err
+	}
+	if len(posts) == 0 {
+		return models.FeedPost{}, ErrPostNotFound
+	}
+
+	return posts[0], nil
+}
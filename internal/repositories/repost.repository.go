@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/radifan9/social-media-backend/internal/models"
+)
+
+var (
+	ErrAlreadyReposted  = errors.New("post already reposted")
+	ErrRepostNotFound   = errors.New("repost not found")
+	ErrRepostPrivate    = errors.New("posts from private accounts cannot be reposted")
+	ErrRepostRestricted = errors.New("only public posts can be reposted")
+)
+
+// Repost shares postID with the followers of userID.
+// Posts of private accounts can only be reposted by their author,
+// and posts shared with a smaller audience cannot be reposted at all
+func (p *PostRepository) Repost(ctx context.Context, userID, postID string) (models.Repost, error) {
+	visible, err := p.canInteract(ctx, userID, postID)
+	if err != nil {
+		return models.Repost{}, err
+	}
+	if !visible {
+		return models.Repost{}, ErrPostNotFound
+	}
+
+	var authorID, visibility string
+	var private bool
+	authorQuery := `
+		SELECT p.user_id, p.visibility, COALESCE(up.is_private, false)
+		FROM posts p
+		LEFT JOIN user_profiles up ON p.user_id = up.user_id
+		WHERE p.id = $1
+	`
+	if err := p.db.QueryRow(ctx, authorQuery, postID).Scan(&authorID, &visibility, &private); err != nil {
+		return models.Repost{}, err
+	}
+	if visibility != models.VisibilityPublic {
+		return models.Repost{}, ErrRepostRestricted
+	}
+	if private && authorID != userID {
+		return models.Repost{}, ErrRepostPrivate
+	}
+
+	query := `
+		INSERT INTO posts (user_id, reposted_post_id)
+		VALUES ($1, $2)
+		ON CONFLICT (reposted_post_id, user_id) WHERE reposted_post_id IS NOT NULL DO NOTHING
+		RETURNING id, user_id, reposted_post_id, created_at
+	`
+
+	var repost models.Repost
+	if err := p.db.QueryRow(ctx, query, userID, postID).Scan(
+		&repost.ID, &repost.UserID, &repost.RepostedPostID, &repost.CreatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Repost{}, ErrAlreadyReposted
+		}
+		return models.Repost{}, err
+	}
+
+	// The repost shows up for the reposter and their followers, and the repost count changed
+	p.InvalidateAuthorFeedCaches(ctx, userID)
+	p.InvalidatePostCache(ctx, postID)
+
+	return repost, nil
+}
+
+// UndoRepost removes the repost of postID by userID
+func (p *PostRepository) UndoRepost(ctx context.Context, userID, postID string) error {
+	query := `DELETE FROM posts WHERE user_id = $1 AND reposted_post_id = $2`
+	tag, err := p.db.Exec(ctx, query, userID, postID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRepostNotFound
+	}
+
+	p.InvalidateAuthorFeedCaches(ctx, userID)
+	p.InvalidatePostCache(ctx, postID)
+
+	return nil
+}
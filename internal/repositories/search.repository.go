@@ -0,0 +1,156 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/radifan9/social-media-backend/pkg"
+)
+
+type SearchRepository struct {
+	db *pgxpool.Pool
+	pr *PostRepository
+}
+
+func NewSearchRepository(db *pgxpool.Pool, pr *PostRepository) *SearchRepository {
+	return &SearchRepository{
+		db: db,
+		pr: pr,
+	}
+}
+
+// SearchPosts ranks posts by full-text relevance of text_content
+func (s *SearchRepository) SearchPosts(ctx context.Context, viewerID, q string, cursorRank *float32, cursorID *string) (models.SearchPage, error) {
+	query := `
+		SELECT id, rank
+		FROM (
+			SELECT p.id, ts_rank_cd(p.search_vector, tq) as rank
+			FROM posts p, websearch_to_tsquery('simple', $1) tq
+			WHERE p.search_vector @@ tq
+				AND p.hidden_at IS NULL
+				AND ` + canViewAuthorSQL("$5::uuid", "p.user_id") + `
+				AND ` + postAudienceSQL("$5::uuid", "p") + `
+		) ranked
+		WHERE $2::real IS NULL OR (rank, id) < ($2, $3::uuid)
+		ORDER BY rank DESC, id DESC
+		LIMIT $4
+	`
+
+	ids, nextCursor, err := s.queryRanked(ctx, query, q, cursorRank, cursorID, pageSize+1, viewerID)
+	if err != nil {
+		return models.SearchPage{}, err
+	}
+
+	posts, err := s.pr.GetFeedPostsByIDs(ctx, viewerID, ids)
+	if err != nil {
+		return models.SearchPage{}, err
+	}
+
+	return models.SearchPage{Posts: posts, NextCursor: nextCursor}, nil
+}
+
+// SearchUsers matches name and bio by full text and names and handles by trigram similarity,
+// so typos still find the account
+func (s *SearchRepository) SearchUsers(ctx context.Context, viewerID, q string, cursorRank *float32, cursorID *string) (models.SearchPage, error) {
+	query := `
+		SELECT user_id, rank
+		FROM (
+			SELECT
+				up.user_id,
+				GREATEST(
+					ts_rank_cd(up.search_vector, tq),
+					similarity(COALESCE(up.name, ''), $1),
+					similarity(COALESCE(up.username, ''), $1)
+				) as rank
+			FROM user_profiles up, websearch_to_tsquery('simple', $1) tq
+			WHERE (up.search_vector @@ tq OR up.name % $1 OR up.username % $1)
+				AND ` + notDeletedSQL("up.user_id") + `
+				AND ` + notBlockedSQL("$5::uuid", "up.user_id") + `
+		) ranked
+		WHERE $2::real IS NULL OR (rank, user_id) < ($2, $3::uuid)
+		ORDER BY rank DESC, user_id DESC
+		LIMIT $4
+	`
+
+	ids, nextCursor, err := s.queryRanked(ctx, query, q, cursorRank, cursorID, pageSize+1, viewerID)
+	if err != nil {
+		return models.SearchPage{}, err
+	}
+
+	users, err := s.getUserSummaries(ctx, ids)
+	if err != nil {
+		return models.SearchPage{}, err
+	}
+
+	return models.SearchPage{Users: users, NextCursor: nextCursor}, nil
+}
+
+// queryRanked reads (id, rank) rows in ranking order, the extra row past pageSize produces the next cursor
+func (s *SearchRepository) queryRanked(ctx context.Context, query string, args ...any) ([]string, string, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var ids []string
+	var lastRank float32
+	var nextCursor string
+	for rows.Next() {
+		var id string
+		var rank float32
+		if err := rows.Scan(&id, &rank); err != nil {
+			return nil, "", err
+		}
+
+		if len(ids) == pageSize {
+			nextCursor = pkg.EncodeRankCursor(lastRank, ids[len(ids)-1])
+			break
+		}
+		ids = append(ids, id)
+		lastRank = rank
+	}
+
+	return ids, nextCursor, rows.Err()
+}
+
+// getUserSummaries loads profiles keeping the order of userIDs
+func (s *SearchRepository) getUserSummaries(ctx context.Context, userIDs []string) ([]models.UserSummary, error) {
+	users := make([]models.UserSummary, 0, len(userIDs))
+	if len(userIDs) == 0 {
+		return users, nil
+	}
+
+	query := `
+		SELECT user_id, username, name, bio, avatar
+		FROM user_profiles
+		WHERE user_id = ANY($1)
+	`
+
+	rows, err := s.db.Query(ctx, query, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := make(map[string]models.UserSummary, len(userIDs))
+	for rows.Next() {
+		var user models.UserSummary
+		if err := rows.Scan(&user.UserID, &user.Username, &user.Name, &user.Bio, &user.Avatar); err != nil {
+			return nil, err
+		}
+		byID[user.UserID] = user
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range userIDs {
+		if user, ok := byID[id]; ok {
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
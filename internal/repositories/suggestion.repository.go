@@ -0,0 +1,183 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/radifan9/social-media-backend/internal/models"
+)
+
+const (
+	// Suggestions kept per user by the worker
+	maxStoredSuggestions = 50
+	// Suggestions returned per request
+	maxSuggestions = 20
+	// Posts newer than this count as recent activity
+	suggestionActivityWindow = 14 * 24 * time.Hour
+)
+
+// RefreshSuggestions recomputes the suggestions of up to limit active users with an id after afterID.
+// It returns the last user id handled, empty when there were no users left
+func (u *UserRepository) RefreshSuggestions(ctx context.Context, afterID string, limit int) (string, error) {
+	query := `
+		SELECT id FROM users
+		WHERE ($1 = '' OR id > NULLIF($1, '')::uuid) AND deleted_at IS NULL
+		ORDER BY id
+		LIMIT $2
+	`
+	rows, err := u.db.Query(ctx, query, afterID, limit)
+	if err != nil {
+		return "", err
+	}
+
+	var userIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return "", err
+		}
+		userIDs = append(userIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	for _, id := range userIDs {
+		if err := u.refreshUserSuggestions(ctx, id); err != nil {
+			log.Printf("Failed to refresh suggestions for user %s: %v", id, err)
+		}
+	}
+
+	if len(userIDs) == 0 {
+		return "", nil
+	}
+	return userIDs[len(userIDs)-1], nil
+}
+
+// refreshUserSuggestions replaces the stored suggestions of userID with friends of friends,
+// scored by how many followed users follow them plus a bonus for posting recently
+func (u *UserRepository) refreshUserSuggestions(ctx context.Context, userID string) error {
+	// Begin transaction
+	tx, err := u.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+				log.Println("failed to rollback transaction:", rollbackErr)
+			}
+		}
+	}()
+
+	if _, err = tx.Exec(ctx, `DELETE FROM follow_suggestions WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	insertQuery := `
+		INSERT INTO follow_suggestions (user_id, suggested_id, mutual_count, score)
+		SELECT $1, c.suggested_id, c.mutual,
+			c.mutual + 0.5 * LN(1 + (
+				SELECT COUNT(*) FROM posts p
+				WHERE p.user_id = c.suggested_id
+					AND p.created_at > $2
+					AND p.hidden_at IS NULL
+			))
+		FROM (
+			SELECT fof.user_id as suggested_id, COUNT(*) as mutual
+			FROM user_followers mine
+			INNER JOIN user_followers fof ON fof.follower_id = mine.user_id
+			WHERE mine.follower_id = $1
+				AND fof.user_id <> $1
+				AND NOT EXISTS (SELECT 1 FROM user_followers f WHERE f.user_id = fof.user_id AND f.follower_id = $1)
+				AND ` + notBlockedSQL("$1", "fof.user_id") + `
+				AND ` + notDeletedSQL("fof.user_id") + `
+			GROUP BY fof.user_id
+		) c
+		ORDER BY 4 DESC, c.suggested_id
+		LIMIT $3
+	`
+	if _, err = tx.Exec(ctx, insertQuery, userID, time.Now().Add(-suggestionActivityWindow), maxStoredSuggestions); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetSuggestions returns the precomputed suggestions of viewerID, leaving out accounts
+// followed, requested or blocked since they were computed. Mutual counts are read fresh
+func (u *UserRepository) GetSuggestions(ctx context.Context, viewerID string) ([]models.FollowSuggestion, error) {
+	query := `
+		SELECT fs.suggested_id, up.username, up.name, up.bio, up.avatar, m.mutual, x.name
+		FROM follow_suggestions fs
+		LEFT JOIN user_profiles up ON fs.suggested_id = up.user_id
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) as mutual
+			FROM user_followers mine
+			INNER JOIN user_followers theirs ON theirs.follower_id = mine.user_id
+			WHERE mine.follower_id = $1 AND theirs.user_id = fs.suggested_id
+				AND ` + notDeletedSQL("mine.user_id") + `
+		) m
+		LEFT JOIN LATERAL (
+			SELECT COALESCE(xp.name, xp.username) as name
+			FROM user_followers mine
+			INNER JOIN user_followers theirs ON theirs.follower_id = mine.user_id
+			LEFT JOIN user_profiles xp ON mine.user_id = xp.user_id
+			WHERE mine.follower_id = $1 AND theirs.user_id = fs.suggested_id
+				AND ` + notDeletedSQL("mine.user_id") + `
+			ORDER BY mine.created_at DESC NULLS LAST
+			LIMIT 1
+		) x ON true
+		WHERE fs.user_id = $1
+			AND m.mutual > 0
+			AND NOT EXISTS (SELECT 1 FROM user_followers f WHERE f.user_id = fs.suggested_id AND f.follower_id = $1)
+			AND NOT EXISTS (SELECT 1 FROM follow_requests fr WHERE fr.target_id = fs.suggested_id AND fr.requester_id = $1)
+			AND ` + notBlockedSQL("$1", "fs.suggested_id") + `
+			AND ` + notDeletedSQL("fs.suggested_id") + `
+		ORDER BY fs.score DESC, fs.suggested_id
+		LIMIT $2
+	`
+
+	rows, err := u.db.Query(ctx, query, viewerID, maxSuggestions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []models.FollowSuggestion{}
+	for rows.Next() {
+		var s models.FollowSuggestion
+		var followedBy *string
+		if err := rows.Scan(&s.UserID, &s.Username, &s.Name, &s.Bio, &s.Avatar, &s.MutualCount, &followedBy); err != nil {
+			return nil, err
+		}
+		s.Explanation = suggestionExplanation(followedBy, s.MutualCount)
+		suggestions = append(suggestions, s)
+	}
+
+	return suggestions, rows.Err()
+}
+
+// suggestionExplanation reads like "Followed by Alice and 3 others you follow"
+func suggestionExplanation(name *string, mutual int) string {
+	first := "someone you follow"
+	if name != nil && *name != "" {
+		first = *name
+	}
+
+	switch mutual {
+	case 1:
+		return "Followed by " + first
+	case 2:
+		return fmt.Sprintf("Followed by %s and 1 other you follow", first)
+	default:
+		return fmt.Sprintf("Followed by %s and %d others you follow", first, mutual-1)
+	}
+}
@@ -0,0 +1,199 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrPrivateAccount = errors.New("this account is private")
+
+// timelineCacheKey is a hash with the first page of each filter combination of a user's timeline
+func timelineCacheKey(userID string) string {
+	return fmt.Sprintf("sosmed:timeline:%s", userID)
+}
+
+// timelinePage is what the timeline cache keeps, the posts themselves come from the post cache
+type timelinePage struct {
+	PostIDs    []string `json:"post_ids"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// GetUserPosts returns the timeline of userID as seen by viewerID, newest first.
+// With replies, posts of other users that userID commented on are included at the time of their latest comment
+func (p *PostRepository) GetUserPosts(ctx context.Context, viewerID, userID string, filter models.TimelineFilter, cursorTime *time.Time, cursorID *string) (models.PostPage, error) {
+	var visible, canView bool
+	accessQuery := `
+		SELECT
+			` + notBlockedSQL("$1::uuid", "u.id") + `,
+			` + canViewAuthorSQL("$1::uuid", "u.id") + `
+		FROM users u
+		WHERE u.id = $2 AND u.deleted_at IS NULL
+	`
+	if err := p.db.QueryRow(ctx, accessQuery, viewerID, userID).Scan(&visible, &canView); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.PostPage{}, ErrUserNotFound
+		}
+		return models.PostPage{}, err
+	}
+	if !visible {
+		return models.PostPage{}, ErrUserNotFound
+	}
+	if !canView {
+		return models.PostPage{}, ErrPrivateAccount
+	}
+
+	// Only the first page is cached, it is the one every profile visit reads
+	cacheField := fmt.Sprintf("media=%t,replies=%t", filter.MediaOnly, filter.WithReplies)
+	var page timelinePage
+	cached := false
+	if cursorTime == nil {
+		cached = p.getCachedTimeline(ctx, userID, cacheField, &page)
+	}
+
+	if !cached {
+		query := `
+			SELECT t.id, t.activity_at
+			FROM (
+				SELECT p.id, p.created_at as activity_at
+				FROM posts p
+				WHERE p.user_id = $1
+					AND p.reposted_post_id IS NULL
+					AND p.hidden_at IS NULL
+					AND (NOT $5::boolean OR ` + hasReadyMediaSQL("p.id") + `)
+				UNION ALL
+				SELECT pc.post_id, MAX(pc.created_at)
+				FROM post_comments pc
+				INNER JOIN posts p ON pc.post_id = p.id
+				WHERE $6::boolean
+					AND pc.user_id = $1
+					AND pc.hidden_at IS NULL
+					AND p.user_id <> $1
+					AND p.hidden_at IS NULL
+					AND (NOT $5::boolean OR ` + hasReadyMediaSQL("p.id") + `)
+				GROUP BY pc.post_id
+			) t
+			WHERE ($2::timestamptz IS NULL OR (t.activity_at, t.id) < ($2, $3::uuid))
+			ORDER BY t.activity_at DESC, t.id DESC
+			LIMIT $4
+		`
+
+		var err error
+		page.PostIDs, page.NextCursor, err = p.queryPostIDPage(ctx, query, userID, cursorTime, cursorID, pageSize+1, filter.MediaOnly, filter.WithReplies)
+		if err != nil {
+			return models.PostPage{}, err
+		}
+
+		if cursorTime == nil {
+			p.setCachedTimeline(ctx, userID, cacheField, page)
+		}
+	}
+
+	// Replies can be on posts the viewer may not see, so the page is always checked against the viewer
+	postIDs, err := p.filterVisiblePosts(ctx, viewerID, page.PostIDs)
+	if err != nil {
+		return models.PostPage{}, err
+	}
+
+	posts, err := p.GetFeedPostsByIDs(ctx, viewerID, postIDs)
+	if err != nil {
+		return models.PostPage{}, err
+	}
+
+	return models.PostPage{Posts: posts, NextCursor: page.NextCursor}, nil
+}
+
+// hasReadyMediaSQL is true when the post has at least one processed image, gif or video
+func hasReadyMediaSQL(postID string) string {
+	return fmt.Sprintf(`EXISTS (
+		SELECT 1 FROM post_images hm
+		WHERE hm.post_id = %s AND hm.status = 'ready'
+	)`, postID)
+}
+
+// filterVisiblePosts keeps the posts viewerID may see, in their original order
+func (p *PostRepository) filterVisiblePosts(ctx context.Context, viewerID string, postIDs []string) ([]string, error) {
+	if len(postIDs) == 0 {
+		return []string{}, nil
+	}
+
+	query := `
+		SELECT p.id
+		FROM posts p
+		WHERE p.id = ANY($1)
+			AND p.hidden_at IS NULL
+			AND ` + canViewAuthorSQL("$2::uuid", "p.user_id") + `
+			AND ` + postAudienceSQL("$2::uuid", "p")
+
+	rows, err := p.db.Query(ctx, query, postIDs, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	visible := make(map[string]bool, len(postIDs))
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		visible[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	filtered := make([]string, 0, len(postIDs))
+	for _, id := range postIDs {
+		if visible[id] {
+			filtered = append(filtered, id)
+		}
+	}
+	return filtered, nil
+}
+
+func (p *PostRepository) getCachedTimeline(ctx context.Context, userID, field string, dest *timelinePage) bool {
+	raw, err := p.rdb.HGet(ctx, timelineCacheKey(userID), field).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Printf("Failed to read timeline cache for user %s: %v", userID, err)
+		}
+		return false
+	}
+
+	if err := json.Unmarshal(raw, dest); err != nil {
+		log.Printf("Failed to decode timeline cache for user %s: %v", userID, err)
+		return false
+	}
+	return true
+}
+
+func (p *PostRepository) setCachedTimeline(ctx context.Context, userID, field string, page timelinePage) {
+	bt, err := json.Marshal(page)
+	if err != nil {
+		log.Printf("Failed to encode timeline cache for user %s: %v", userID, err)
+		return
+	}
+
+	key := timelineCacheKey(userID)
+	pipe := p.rdb.TxPipeline()
+	pipe.HSet(ctx, key, field, bt)
+	pipe.Expire(ctx, key, 5*time.Minute)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to write timeline cache for user %s: %v", userID, err)
+	}
+}
+
+// InvalidateTimelineCache drops every cached first page of the timeline of userID
+func (p *PostRepository) InvalidateTimelineCache(ctx context.Context, userID string) {
+	if err := p.rdb.Del(ctx, timelineCacheKey(userID)).Err(); err != nil {
+		log.Printf("Failed to invalidate timeline cache for user %s: %v", userID, err)
+	}
+}
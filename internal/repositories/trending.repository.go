@@ -0,0 +1,206 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// Interaction kinds counted towards trending, with how much each one is worth
+const (
+	trendingLike    = "like"
+	trendingComment = "comment"
+)
+
+var trendingKindWeight = map[string]float64{
+	trendingLike:    1,
+	trendingComment: 2,
+}
+
+var ErrInvalidTrendingWindow = errors.New("window must be 1h or 24h")
+
+// trendingWindow is a sliding window made of fixed buckets. Older buckets weigh less,
+// halving every halfLife, so the window slides smoothly instead of dropping a whole bucket at once
+type trendingWindow struct {
+	name     string
+	length   time.Duration
+	bucket   time.Duration
+	halfLife time.Duration
+}
+
+var trendingWindows = []trendingWindow{
+	{name: "1h", length: time.Hour, bucket: 5 * time.Minute, halfLife: 30 * time.Minute},
+	{name: "24h", length: 24 * time.Hour, bucket: time.Hour, halfLife: 6 * time.Hour},
+}
+
+const (
+	// How long a computed ranking is reused before the buckets are merged again
+	trendingViewTTL = time.Minute
+	trendingTopN    = 10
+	// Trending posts are read past the top N because some may be hidden from the viewer
+	trendingPostCandidates = 50
+)
+
+func trendingBucketKey(kind string, w trendingWindow, start time.Time) string {
+	return fmt.Sprintf("sosmed:trending:%s:%s:%d", kind, w.name, start.Unix())
+}
+
+func trendingViewKey(kind string, w trendingWindow) string {
+	return fmt.Sprintf("sosmed:trending:%s:%s:view", kind, w.name)
+}
+
+// trendingVoteKey marks that userID already counted for postID, whatever the kind of interaction
+func trendingVoteKey(postID, userID string) string {
+	return fmt.Sprintf("sosmed:trending:vote:%s:%s", postID, userID)
+}
+
+// recordTrendingEvent counts a like or comment of userID on postID towards trending posts
+// and the hashtags of the post. Only public posts trend. Every user votes once per post, with
+// the weight of their first like or comment, and interactions with your own posts are ignored.
+// Failures are only logged
+func (p *PostRepository) recordTrendingEvent(ctx context.Context, userID, postID, kind string) {
+	var authorID, visibility string
+	var tags []string
+	query := `
+		SELECT p.user_id, p.visibility, ARRAY(
+			SELECT DISTINCT h.tag
+			FROM post_hashtags ph
+			INNER JOIN hashtags h ON ph.hashtag_id = h.id
+			WHERE ph.post_id = p.id AND ph.comment_id IS NULL
+		)
+		FROM posts p
+		WHERE p.id = $1
+	`
+	if err := p.db.QueryRow(ctx, query, postID).Scan(&authorID, &visibility, &tags); err != nil {
+		log.Printf("Failed to read post %s for trending: %v", postID, err)
+		return
+	}
+	if authorID == userID || visibility != models.VisibilityPublic {
+		return
+	}
+
+	// The vote marker lives as long as the longest window
+	voteKey := trendingVoteKey(postID, userID)
+	first, err := p.rdb.SetNX(ctx, voteKey, 1, trendingWindows[len(trendingWindows)-1].length).Result()
+	if err != nil {
+		log.Printf("Failed to record trending vote: %v", err)
+		return
+	}
+	if !first {
+		return
+	}
+
+	now := time.Now()
+	weight := trendingKindWeight[kind]
+	pipe := p.rdb.Pipeline()
+	for _, w := range trendingWindows {
+		start := now.Truncate(w.bucket)
+
+		postKey := trendingBucketKey("posts", w, start)
+		pipe.ZIncrBy(ctx, postKey, weight, postID)
+		pipe.Expire(ctx, postKey, w.length+w.bucket)
+
+		for _, tag := range tags {
+			tagKey := trendingBucketKey("tags", w, start)
+			pipe.ZIncrBy(ctx, tagKey, weight, tag)
+			pipe.Expire(ctx, tagKey, w.length+w.bucket)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to record trending event: %v", err)
+
+		// Without the marker the next interaction of the user can count instead
+		if err := p.rdb.Del(ctx, voteKey).Err(); err != nil {
+			log.Printf("Failed to clear trending vote: %v", err)
+		}
+	}
+}
+
+// GetTrending returns the top posts visible to viewerID and the top hashtags of the window
+func (p *PostRepository) GetTrending(ctx context.Context, viewerID, windowName string) (models.Trending, error) {
+	var window *trendingWindow
+	for i := range trendingWindows {
+		if trendingWindows[i].name == windowName {
+			window = &trendingWindows[i]
+		}
+	}
+	if window == nil {
+		return models.Trending{}, ErrInvalidTrendingWindow
+	}
+
+	ranked, err := p.trendingRanking(ctx, "posts", *window, trendingPostCandidates)
+	if err != nil {
+		return models.Trending{}, err
+	}
+
+	postIDs := make([]string, 0, len(ranked))
+	for _, z := range ranked {
+		if id, ok := z.Member.(string); ok {
+			postIDs = append(postIDs, id)
+		}
+	}
+	if postIDs, err = p.filterVisiblePosts(ctx, viewerID, postIDs); err != nil {
+		return models.Trending{}, err
+	}
+	if len(postIDs) > trendingTopN {
+		postIDs = postIDs[:trendingTopN]
+	}
+
+	posts, err := p.GetFeedPostsByIDs(ctx, viewerID, postIDs)
+	if err != nil {
+		return models.Trending{}, err
+	}
+
+	rankedTags, err := p.trendingRanking(ctx, "tags", *window, trendingTopN)
+	if err != nil {
+		return models.Trending{}, err
+	}
+
+	topics := make([]models.TrendingTopic, 0, len(rankedTags))
+	for _, z := range rankedTags {
+		if tag, ok := z.Member.(string); ok {
+			topics = append(topics, models.TrendingTopic{Tag: tag, Score: math.Round(z.Score*100) / 100})
+		}
+	}
+
+	return models.Trending{Window: window.name, Posts: posts, Topics: topics}, nil
+}
+
+// trendingRanking merges the buckets of the window with decayed weights into a view
+// that is reused for a minute, and returns its top members
+func (p *PostRepository) trendingRanking(ctx context.Context, kind string, w trendingWindow, limit int) ([]redis.Z, error) {
+	viewKey := trendingViewKey(kind, w)
+
+	exists, err := p.rdb.Exists(ctx, viewKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if exists == 0 {
+		now := time.Now()
+		current := now.Truncate(w.bucket)
+
+		store := &redis.ZStore{Aggregate: "SUM"}
+		for i := 0; i < int(w.length/w.bucket); i++ {
+			start := current.Add(-time.Duration(i) * w.bucket)
+			age := now.Sub(start)
+			store.Keys = append(store.Keys, trendingBucketKey(kind, w, start))
+			store.Weights = append(store.Weights, math.Exp2(-age.Hours()/w.halfLife.Hours()))
+		}
+
+		pipe := p.rdb.TxPipeline()
+		pipe.ZUnionStore(ctx, viewKey, store)
+		pipe.Expire(ctx, viewKey, trendingViewTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.rdb.ZRevRangeWithScores(ctx, viewKey, 0, int64(limit-1)).Result()
+}
@@ -5,11 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/radifan9/social-media-backend/internal/utils"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -25,7 +28,19 @@ func NewUserRepository(db *pgxpool.Pool, rdb *redis.Client) *UserRepository {
 	}
 }
 
-func (u *UserRepository) CreateUser(ctx context.Context, email, hashedPassword string) (models.User, error) {
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrUsernameTaken    = errors.New("username is already taken")
+	ErrUsernameCooldown = errors.New("username was changed recently")
+)
+
+// isUniqueViolation reports whether err is a unique_violation on the given constraint or index
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == constraint
+}
+
+func (u *UserRepository) CreateUser(ctx context.Context, email, hashedPassword, username string) (models.User, error) {
 	// Begin transaction
 	tx, err := u.db.Begin(ctx)
 	if err != nil {
@@ -48,14 +63,20 @@ func (u *UserRepository) CreateUser(ctx context.Context, email, hashedPassword s
 			email`
 	var user models.User
 
-	if err := u.db.QueryRow(ctx, query, email, hashedPassword).Scan(&user.Id, &user.Email); err != nil {
+	if err = tx.QueryRow(ctx, query, email, hashedPassword).Scan(&user.Id, &user.Email); err != nil {
+		if isUniqueViolation(err, "users_email_key") {
+			return models.User{}, ErrEmailTaken
+		}
 		return models.User{}, fmt.Errorf("failed to register user: %w", err)
 	}
 
 	// Step 2: Create Profile
 	// var profileID string
-	_, err = u.createProfile(ctx, tx, user.Id)
+	_, err = u.createProfile(ctx, tx, user.Id, username)
 	if err != nil {
+		if isUniqueViolation(err, "user_profiles_username_key") {
+			return models.User{}, ErrUsernameTaken
+		}
 		return models.User{}, err
 	}
 
@@ -67,15 +88,15 @@ func (u *UserRepository) CreateUser(ctx context.Context, email, hashedPassword s
 	return user, nil
 }
 
-func (u *UserRepository) createProfile(ctx context.Context, tx pgx.Tx, userID string) (string, error) {
+func (u *UserRepository) createProfile(ctx context.Context, tx pgx.Tx, userID, username string) (string, error) {
 	query := `
 		insert into
-			user_profiles (user_id)
+			user_profiles (user_id, username, username_changed_at)
 		values
-			($1) returning user_id`
+			($1, NULLIF($2, ''), CASE WHEN $2 = '' THEN NULL ELSE CURRENT_TIMESTAMP END) returning user_id`
 
 	var id string
-	err := tx.QueryRow(ctx, query, userID).Scan(&id)
+	err := tx.QueryRow(ctx, query, userID, username).Scan(&id)
 	if err != nil {
 		return "", err
 	}
@@ -95,20 +116,45 @@ func (u *UserRepository) GetIDFromEmail(ctx context.Context, email string) (mode
 }
 
 func (u *UserRepository) GetPasswordFromID(ctx context.Context, id string) (models.User, error) {
-	query := `SELECT password FROM users WHERE id = $1`
+	query := `SELECT password, role, status, suspended_until, deleted_at FROM users WHERE id = $1`
 
 	var user models.User
 
-	if err := u.db.QueryRow(ctx, query, id).Scan(&user.Password); err != nil {
+	if err := u.db.QueryRow(ctx, query, id).Scan(&user.Password, &user.Role, &user.Status, &user.SuspendedUntil, &user.DeletedAt); err != nil {
 		return models.User{}, errors.New("failed to login")
 	}
 	return user, nil
 }
 
-func (u *UserRepository) EditProfile(ctx context.Context, userID string, body models.EditUserProfile, avatarPath string) (models.UserProfile, error) {
-	sql := "UPDATE user_profiles SET "
+// EditProfile updates the profile and also returns the avatar it replaced
+func (u *UserRepository) EditProfile(ctx context.Context, userID string, body models.EditUserProfile, avatarPath string) (models.UserProfile, string, error) {
+	sql := "UPDATE user_profiles up SET "
 	values := []any{}
 
+	if body.Username != "" {
+		var current *string
+		var changedAt *time.Time
+		currentQuery := `SELECT username, username_changed_at FROM user_profiles WHERE user_id = $1`
+		if err := u.db.QueryRow(ctx, currentQuery, userID).Scan(&current, &changedAt); err != nil {
+			return models.UserProfile{}, "", err
+		}
+
+		switch {
+		case current != nil && *current == body.Username:
+			// Nothing to change
+		case current != nil && strings.EqualFold(*current, body.Username):
+			// Only the casing changes, which does not count as a rename
+			sql += fmt.Sprintf("%s=$%d, ", "username", len(values)+1)
+			values = append(values, body.Username)
+		default:
+			if changedAt != nil && time.Since(*changedAt) < utils.UsernameCooldown {
+				return models.UserProfile{}, "", fmt.Errorf("%w, next change allowed after %s", ErrUsernameCooldown, changedAt.Add(utils.UsernameCooldown).Format(time.RFC3339))
+			}
+			sql += fmt.Sprintf("%s=$%d, username_changed_at=CURRENT_TIMESTAMP, ", "username", len(values)+1)
+			values = append(values, body.Username)
+		}
+	}
+
 	if body.Name != "" {
 		sql += fmt.Sprintf("%s=$%d, ", "name", len(values)+1)
 		values = append(values, body.Name)
@@ -124,59 +170,191 @@ func (u *UserRepository) EditProfile(ctx context.Context, userID string, body mo
 		values = append(values, avatarPath)
 	}
 
+	if body.IsPrivate != nil {
+		sql += fmt.Sprintf("%s=$%d, ", "is_private", len(values)+1)
+		values = append(values, *body.IsPrivate)
+	}
+
 	// sql += fmt.Sprintf("updated_at=CURRENT_TIMESTAMP WHERE user_id=$%d RETURNING user_id, first_name, last_name, img, phone_number, points, created_at, updated_at", len(values)+1)
 	sql += fmt.Sprintf(`updated_at=CURRENT_TIMESTAMP 
-    WHERE user_id=$%d 
+    FROM (SELECT avatar FROM user_profiles WHERE user_id=$%[1]d) old
+    WHERE up.user_id=$%[1]d 
     RETURNING 
-        user_id, 
-        COALESCE(name, ''), 
-        COALESCE(bio, ''), 
-        COALESCE(avatar, ''), 
-        created_at, 
-        updated_at`, len(values)+1)
+        up.user_id, 
+        COALESCE(up.username, ''), 
+        COALESCE(up.name, ''), 
+        COALESCE(up.bio, ''), 
+        COALESCE(up.avatar, ''), 
+        up.is_private, 
+        up.created_at, 
+        up.updated_at,
+        COALESCE(old.avatar, '')`, len(values)+1)
 
 	values = append(values, userID)
 
 	log.Println("SQL : ", sql)
 
 	var profile models.UserProfile
+	var oldAvatar string
 	if err := u.db.QueryRow(ctx, sql, values...).Scan(
 		&profile.UserID,
+		&profile.Username,
+		&profile.Name,
+		&profile.Bio,
+		&profile.Avatar,
+		&profile.IsPrivate,
+		&profile.CreatedAt,
+		&profile.UpdatedAt,
+		&oldAvatar,
+	); err != nil {
+		if isUniqueViolation(err, "user_profiles_username_key") {
+			return models.UserProfile{}, "", ErrUsernameTaken
+		}
+		return models.UserProfile{}, "", err
+	}
+
+	// Going public lets everyone who asked in
+	if body.IsPrivate != nil && !*body.IsPrivate {
+		if err := u.approveAllFollowRequests(ctx, userID); err != nil {
+			log.Printf("Failed to approve pending follow requests: %v", err)
+		}
+	}
+
+	return profile, oldAvatar, nil
+}
+
+// GetProfileByUsername looks up a profile by handle, ignoring case.
+// A block in either direction reports the user as not found
+func (u *UserRepository) GetProfileByUsername(ctx context.Context, viewerID, username string) (models.UserProfile, error) {
+	query := `
+		SELECT
+			user_id,
+			username,
+			COALESCE(name, ''),
+			COALESCE(bio, ''),
+			COALESCE(avatar, ''),
+			is_private,
+			created_at,
+			updated_at
+		FROM user_profiles
+		WHERE LOWER(username) = LOWER($1)
+			AND ` + notDeletedSQL("user_id") + `
+			AND ` + notBlockedSQL("$2", "user_id")
+
+	var profile models.UserProfile
+	if err := u.db.QueryRow(ctx, query, username, viewerID).Scan(
+		&profile.UserID,
+		&profile.Username,
 		&profile.Name,
 		&profile.Bio,
 		&profile.Avatar,
+		&profile.IsPrivate,
 		&profile.CreatedAt,
 		&profile.UpdatedAt,
 	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.UserProfile{}, ErrUserNotFound
+		}
 		return models.UserProfile{}, err
 	}
 
 	return profile, nil
 }
 
-var ErrAlreadyFollowed = errors.New("user already followed this account")
+// ResolveUserID accepts either a user id or a handle (with or without "@") and returns the user id
+func (u *UserRepository) ResolveUserID(ctx context.Context, idOrHandle string) (string, error) {
+	var query string
+	if utils.IsUUID(idOrHandle) {
+		query = `SELECT id FROM users WHERE id = $1 AND deleted_at IS NULL`
+	} else {
+		idOrHandle = strings.TrimPrefix(idOrHandle, "@")
+		query = `SELECT user_id FROM user_profiles WHERE LOWER(username) = LOWER($1) AND ` + notDeletedSQL("user_id")
+	}
+
+	var userID string
+	if err := u.db.QueryRow(ctx, query, idOrHandle).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+
+	return userID, nil
+}
+
+var (
+	ErrAlreadyFollowed  = errors.New("user already followed this account")
+	ErrAlreadyRequested = errors.New("follow request already sent")
+)
 
-func (u *UserRepository) FollowUser(ctx context.Context, whoFollow, targetFollow string) error {
+// FollowUser follows targetFollow. For private accounts a follow request is created instead
+// and requested is true
+func (u *UserRepository) FollowUser(ctx context.Context, whoFollow, targetFollow string) (requested bool, err error) {
 	log.Println("who follow ID", whoFollow)
 	log.Println("target follow ID", targetFollow)
 
+	if whoFollow == targetFollow {
+		return false, ErrSelfAction
+	}
+
+	var isPrivate, isFollowing bool
+	privacyQuery := `
+		SELECT
+			up.is_private,
+			EXISTS (SELECT 1 FROM user_followers WHERE user_id = $1 AND follower_id = $2)
+		FROM user_profiles up
+		WHERE up.user_id = $1
+			AND ` + notDeletedSQL("up.user_id") + `
+			AND ` + notBlockedSQL("$1", "$2::uuid")
+	if err := u.db.QueryRow(ctx, privacyQuery, targetFollow, whoFollow).Scan(&isPrivate, &isFollowing); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, ErrUserNotFound
+		}
+		return false, err
+	}
+
+	if isFollowing {
+		return false, ErrAlreadyFollowed
+	}
+
+	if isPrivate {
+		requestQuery := `
+			insert into
+				follow_requests (requester_id, target_id)
+			values
+				($1, $2)
+		`
+		if _, err := u.db.Exec(ctx, requestQuery, whoFollow, targetFollow); err != nil {
+			if isUniqueViolation(err, "follow_requests_pkey") {
+				return false, ErrAlreadyRequested
+			}
+			return false, err
+		}
+		return true, nil
+	}
+
+	// Nothing is inserted when either user blocked the other
 	query := `
 		insert into
 			user_followers (user_id, follower_id)
-		values
-			($1, $2)
-	`
+		select
+			$1, $2
+		where ` + notBlockedSQL("$1::uuid", "$2::uuid")
 
-	_, err := u.db.Exec(ctx, query, targetFollow, whoFollow)
+	tag, err := u.db.Exec(ctx, query, targetFollow, whoFollow)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == "23505" { // unqiue_violation
-				return ErrAlreadyFollowed
+				return false, ErrAlreadyFollowed
 			}
 		}
-		return err
+		return false, err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return false, ErrUserNotFound
 	}
 
-	return nil
+	return false, nil
 }
@@ -0,0 +1,27 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radifan9/social-media-backend/internal/handlers"
+	"github.com/radifan9/social-media-backend/internal/middlewares"
+	"github.com/radifan9/social-media-backend/internal/repositories"
+	"github.com/redis/go-redis/v9"
+)
+
+func RegisterMessageRoutes(v1 *gin.RouterGroup, db *pgxpool.Pool, rdb *redis.Client) {
+	messageRepo := repositories.NewMessageRepository(db, rdb)
+	messageHandler := handlers.NewMessageHandler(messageRepo)
+	verifyTokenWithBlacklist := middlewares.VerifyTokenWithBlacklist(rdb)
+
+	conversations := v1.Group("/conversations")
+	conversations.Use(verifyTokenWithBlacklist)
+	conversations.POST("/", messageHandler.CreateConversation)
+	conversations.GET("/", messageHandler.GetConversations)
+	conversations.GET("/unread", messageHandler.GetUnreadCount)
+	conversations.GET("/stream", messageHandler.StreamMessages)
+	conversations.GET("/:id", messageHandler.GetConversation)
+	conversations.GET("/:id/messages", messageHandler.GetMessages)
+	conversations.POST("/:id/messages", messageHandler.SendMessage)
+	conversations.POST("/:id/read", messageHandler.MarkRead)
+}
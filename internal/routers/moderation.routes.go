@@ -0,0 +1,30 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radifan9/social-media-backend/internal/handlers"
+	"github.com/radifan9/social-media-backend/internal/middlewares"
+	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/radifan9/social-media-backend/internal/repositories"
+	"github.com/redis/go-redis/v9"
+)
+
+func RegisterModerationRoutes(v1 *gin.RouterGroup, db *pgxpool.Pool, rdb *redis.Client) {
+	moderationRepo := repositories.NewModerationRepository(db, rdb)
+	userRepo := repositories.NewUserRepository(db, rdb)
+	moderationHandler := handlers.NewModerationHandler(moderationRepo, userRepo)
+	verifyTokenWithBlacklist := middlewares.VerifyTokenWithBlacklist(rdb)
+
+	v1.POST("/report", verifyTokenWithBlacklist, moderationHandler.CreateReport)
+
+	moderation := v1.Group("/moderation")
+	moderation.Use(verifyTokenWithBlacklist, middlewares.Access(models.RoleModerator, models.RoleAdmin))
+	moderation.GET("/reports", moderationHandler.ListReports)
+	moderation.POST("/reports/:id/claim", moderationHandler.ClaimReport)
+	moderation.POST("/reports/:id/resolve", moderationHandler.ResolveReport)
+
+	admin := v1.Group("/admin")
+	admin.Use(verifyTokenWithBlacklist, middlewares.Access(models.RoleAdmin))
+	admin.PATCH("/users/:targetID/status", moderationHandler.SetAccountStatus)
+}
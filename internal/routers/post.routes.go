@@ -3,22 +3,47 @@ package routers
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radifan9/social-media-backend/internal/filters"
 	"github.com/radifan9/social-media-backend/internal/handlers"
 	"github.com/radifan9/social-media-backend/internal/middlewares"
+	"github.com/radifan9/social-media-backend/internal/ranking"
 	"github.com/radifan9/social-media-backend/internal/repositories"
 	"github.com/redis/go-redis/v9"
 )
 
-func RegisterPostRoutes(v1 *gin.RouterGroup, db *pgxpool.Pool, rdb *redis.Client) {
+func RegisterPostRoutes(v1 *gin.RouterGroup, db *pgxpool.Pool, rdb *redis.Client, contentFilter filters.ContentFilter, rankingWeights ranking.Weights) {
 	postRepo := repositories.NewPostRepository(db, rdb)
-	postHandler := handlers.NewPostHandler(postRepo, rdb)
+	postHandler := handlers.NewPostHandler(postRepo, rdb, contentFilter, rankingWeights)
 	verifyTokenWithBlacklist := middlewares.VerifyTokenWithBlacklist(rdb)
 
 	post := v1.Group("/post")
 	post.POST("/", verifyTokenWithBlacklist, postHandler.CreatePost)
 	post.POST("/like", verifyTokenWithBlacklist, postHandler.LikePost)
 	post.POST("/comment", verifyTokenWithBlacklist, postHandler.AddComment)
+	post.GET("/:id", verifyTokenWithBlacklist, postHandler.GetPostDetail)
+	post.POST("/:id/repost", verifyTokenWithBlacklist, postHandler.Repost)
+	post.DELETE("/:id/repost", verifyTokenWithBlacklist, postHandler.UndoRepost)
+	post.POST("/:id/quote", verifyTokenWithBlacklist, postHandler.QuotePost)
+	post.POST("/:id/bookmark", verifyTokenWithBlacklist, postHandler.BookmarkPost)
+	post.DELETE("/:id/bookmark", verifyTokenWithBlacklist, postHandler.RemoveBookmark)
+	post.POST("/:id/poll/vote", verifyTokenWithBlacklist, postHandler.VotePoll)
+
+	bookmarks := v1.Group("/bookmarks")
+	bookmarks.GET("/", verifyTokenWithBlacklist, postHandler.GetBookmarks)
+
+	drafts := v1.Group("/drafts")
+	drafts.POST("/", verifyTokenWithBlacklist, postHandler.CreateDraft)
+	drafts.GET("/", verifyTokenWithBlacklist, postHandler.GetDrafts)
+	drafts.PATCH("/:id", verifyTokenWithBlacklist, postHandler.EditDraft)
+	drafts.DELETE("/:id", verifyTokenWithBlacklist, postHandler.CancelDraft)
 
 	feed := v1.Group("/feed")
 	feed.GET("/", verifyTokenWithBlacklist, postHandler.GetFollowingFeed)
+	feed.GET("/for-you", verifyTokenWithBlacklist, postHandler.GetForYouFeed)
+
+	trending := v1.Group("/trending")
+	trending.GET("/", verifyTokenWithBlacklist, postHandler.GetTrending)
+
+	hashtag := v1.Group("/hashtag")
+	hashtag.GET("/:tag/posts", verifyTokenWithBlacklist, postHandler.GetHashtagPosts)
 }
@@ -6,14 +6,16 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/radifan9/social-media-backend/docs"
+	"github.com/radifan9/social-media-backend/internal/filters"
 	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/radifan9/social-media-backend/internal/ranking"
 	"github.com/radifan9/social-media-backend/internal/utils"
 	"github.com/redis/go-redis/v9"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func InitRouter(db *pgxpool.Pool, rdb *redis.Client) *gin.Engine {
+func InitRouter(db *pgxpool.Pool, rdb *redis.Client, contentFilter filters.ContentFilter, rankingWeights ranking.Weights) *gin.Engine {
 	router := gin.Default()
 
 	// Swagger
@@ -24,7 +26,10 @@ func InitRouter(db *pgxpool.Pool, rdb *redis.Client) *gin.Engine {
 	v1 := router.Group("/api/v1")
 	{
 		RegisterUserRoutes(v1, db, rdb)
-		RegisterPostRoutes(v1, db, rdb)
+		RegisterPostRoutes(v1, db, rdb, contentFilter, rankingWeights)
+		RegisterSearchRoutes(v1, db, rdb)
+		RegisterModerationRoutes(v1, db, rdb)
+		RegisterMessageRoutes(v1, db, rdb)
 
 		// Static File Image
 		v1.Static("/img", "public")
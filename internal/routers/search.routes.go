@@ -0,0 +1,19 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radifan9/social-media-backend/internal/handlers"
+	"github.com/radifan9/social-media-backend/internal/middlewares"
+	"github.com/radifan9/social-media-backend/internal/repositories"
+	"github.com/redis/go-redis/v9"
+)
+
+func RegisterSearchRoutes(v1 *gin.RouterGroup, db *pgxpool.Pool, rdb *redis.Client) {
+	postRepo := repositories.NewPostRepository(db, rdb)
+	searchRepo := repositories.NewSearchRepository(db, postRepo)
+	searchHandler := handlers.NewSearchHandler(searchRepo)
+	verifyTokenWithBlacklist := middlewares.VerifyTokenWithBlacklist(rdb)
+
+	v1.GET("/search", verifyTokenWithBlacklist, searchHandler.Search)
+}
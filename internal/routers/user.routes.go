@@ -11,16 +11,39 @@ import (
 
 func RegisterUserRoutes(v1 *gin.RouterGroup, db *pgxpool.Pool, rdb *redis.Client) {
 	userRepo := repositories.NewUserRepository(db, rdb)
-	userHandler := handlers.NewUserHandler(userRepo, rdb)
+	postRepo := repositories.NewPostRepository(db, rdb)
+	userHandler := handlers.NewUserHandler(userRepo, postRepo, rdb)
 	verifyTokenWithBlacklist := middlewares.VerifyTokenWithBlacklist(rdb)
 
 	auth := v1.Group("/auth")
 	auth.POST("/register", userHandler.Register)
 	auth.POST("/login", userHandler.Login)
 	auth.DELETE("/logout", verifyTokenWithBlacklist, userHandler.Logout)
+	auth.GET("/email/confirm", userHandler.ShowEmailConfirmation)
+	auth.POST("/email/confirm", userHandler.ConfirmEmailChange)
+	auth.GET("/email/revert", userHandler.ShowEmailRevert)
+	auth.POST("/email/revert", userHandler.RevertEmailChange)
 
 	user := v1.Group("/user")
 	user.Use(verifyTokenWithBlacklist)
 	user.PATCH("/", userHandler.EditProfile)
+	user.DELETE("/me", userHandler.DeleteAccount)
+	user.POST("/me/export", userHandler.ExportData)
+	user.POST("/me/email", userHandler.RequestEmailChange)
+	user.GET("/by-handle/:username", userHandler.GetProfileByHandle)
+	user.GET("/suggestions", userHandler.GetSuggestions)
 	user.POST("/:targetID/follow", userHandler.FollowUser)
+	user.GET("/:targetID/posts", userHandler.GetUserPosts)
+	user.GET("/follow-requests", userHandler.GetFollowRequests)
+	user.POST("/follow-requests/:requesterID/approve", userHandler.ApproveFollowRequest)
+	user.POST("/follow-requests/:requesterID/reject", userHandler.RejectFollowRequest)
+	user.GET("/blocks", userHandler.GetBlockedUsers)
+	user.POST("/:targetID/block", userHandler.BlockUser)
+	user.DELETE("/:targetID/block", userHandler.UnblockUser)
+	user.GET("/mutes", userHandler.GetMutedUsers)
+	user.POST("/:targetID/mute", userHandler.MuteUser)
+	user.DELETE("/:targetID/mute", userHandler.UnmuteUser)
+	user.GET("/close-friends", userHandler.GetCloseFriends)
+	user.POST("/:targetID/close-friend", userHandler.AddCloseFriend)
+	user.DELETE("/:targetID/close-friend", userHandler.RemoveCloseFriend)
 }
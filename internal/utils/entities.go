@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/radifan9/social-media-backend/internal/models"
+)
+
+// The leading group makes sure the sigil is not glued to a previous word (e.g. emails)
+var (
+	hashtagRe = regexp.MustCompile(`(^|[^\p{L}\p{N}_&/])#([\p{L}\p{N}_]{1,100})`)
+	mentionRe = regexp.MustCompile(`(^|[^\p{L}\p{N}_.@/])@([A-Za-z0-9_]{1,30})`)
+)
+
+// ParseEntities finds #hashtags and @mentions in text.
+// Offsets are counted in code points and include the sigil, End is exclusive
+func ParseEntities(text string) []models.Entity {
+	var entities []models.Entity
+
+	for _, m := range hashtagRe.FindAllStringSubmatchIndex(text, -1) {
+		tag := text[m[4]:m[5]]
+		// "#2024" is a number, not a topic
+		if strings.IndexFunc(tag, unicode.IsLetter) < 0 {
+			continue
+		}
+		entities = append(entities, models.Entity{
+			Type:  models.EntityHashtag,
+			Text:  strings.ToLower(tag),
+			Start: utf8.RuneCountInString(text[:m[4]-1]),
+			End:   utf8.RuneCountInString(text[:m[5]]),
+		})
+	}
+
+	for _, m := range mentionRe.FindAllStringSubmatchIndex(text, -1) {
+		entities = append(entities, models.Entity{
+			Type:  models.EntityMention,
+			Text:  text[m[4]:m[5]],
+			Start: utf8.RuneCountInString(text[:m[4]-1]),
+			End:   utf8.RuneCountInString(text[:m[5]]),
+		})
+	}
+
+	sort.Slice(entities, func(i, j int) bool { return entities[i].Start < entities[j].Start })
+	return entities
+}
@@ -16,6 +16,17 @@ func HandleError(ctx *gin.Context, status int, err string, logMsg string) {
 	})
 }
 
+// HandleErrorWithCode is HandleError with a machine readable code clients can switch on
+func HandleErrorWithCode(ctx *gin.Context, status int, code string, err string, logMsg string) {
+	log.Printf("%s\nCause: %s\n", logMsg, err)
+	ctx.JSON(status, models.ErrorResponse{
+		Success: false,
+		Status:  status,
+		Error:   err,
+		Code:    code,
+	})
+}
+
 func HandleMiddlewareError(ctx *gin.Context, status int, err string, logMsg string) {
 	log.Printf("%s\nCause: %s\n", logMsg, err)
 	ctx.AbortWithStatusJSON(status, models.ErrorResponse{
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/radifan9/social-media-backend/internal/models"
+)
+
+// WriteExportZip writes the JSON sections of a data export and its uploaded files as a ZIP archive.
+// Files under public/ end up in media/, e.g. media/post_images/<name>. Missing files are skipped
+func WriteExportZip(w io.Writer, export models.DataExport) error {
+	zw := zip.NewWriter(w)
+
+	names := make([]string, 0, len(export.Sections))
+	for name := range export.Sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(export.Sections[name]); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range export.MediaFiles {
+		if err := addFileToZip(zw, path, "media/"+strings.TrimPrefix(filepath.ToSlash(path), "public/")); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				log.Printf("Export skipped missing file %s", path)
+				continue
+			}
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func addFileToZip(zw *zip.Writer, path, name string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+)
+
+// RemoveFiles deletes uploaded files, used to undo uploads when a request fails
+func RemoveFiles(paths ...string) {
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Failed to remove file %s: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// UsernameCooldown is how long a user has to wait between handle changes
+const UsernameCooldown = 30 * 24 * time.Hour
+
+var (
+	ErrUsernameFormat   = errors.New("username must be 3-30 letters, digits or underscores and contain a letter")
+	ErrUsernameReserved = errors.New("username is reserved")
+)
+
+var (
+	usernameRe = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+	uuidRe     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// Handles that would clash with routes, staff accounts or impersonation
+var reservedUsernames = map[string]struct{}{
+	"admin": {}, "administrator": {}, "root": {}, "system": {}, "support": {},
+	"help": {}, "moderator": {}, "mod": {}, "staff": {}, "official": {},
+	"api": {}, "auth": {}, "login": {}, "logout": {}, "register": {},
+	"settings": {}, "me": {}, "user": {}, "users": {}, "by_handle": {},
+	"post": {}, "posts": {}, "feed": {}, "search": {}, "hashtag": {},
+	"notifications": {}, "explore": {}, "trending": {}, "null": {}, "undefined": {},
+}
+
+// ValidateUsername checks format and the reserved list
+func ValidateUsername(username string) error {
+	if !usernameRe.MatchString(username) || strings.IndexFunc(username, unicode.IsLetter) < 0 {
+		return ErrUsernameFormat
+	}
+	if _, ok := reservedUsernames[strings.ToLower(username)]; ok {
+		return ErrUsernameReserved
+	}
+	return nil
+}
+
+// IsUUID reports whether s looks like a user id rather than a handle
+func IsUUID(s string) bool {
+	return uuidRe.MatchString(s)
+}
@@ -0,0 +1,74 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/radifan9/social-media-backend/internal/repositories"
+)
+
+// Directories under public/ that hold user uploads
+var mediaDirs = []string{"public/post_images", "public/avatars"}
+
+type CleanupResult struct {
+	Scanned int
+	Removed int
+	Bytes   int64
+}
+
+// CleanupMedia removes files in public/ that no post or profile references.
+// Files younger than grace are kept so uploads still in flight are not removed
+func CleanupMedia(ctx context.Context, mr *repositories.MediaRepository, grace time.Duration, dryRun bool) (CleanupResult, error) {
+	var result CleanupResult
+
+	referenced, err := mr.ListReferencedMedia(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	cutoff := time.Now().Add(-grace)
+	for _, dir := range mediaDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return result, err
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			result.Scanned++
+
+			if _, ok := referenced[entry.Name()]; ok {
+				continue
+			}
+
+			info, err := entry.Info()
+			if err != nil {
+				log.Printf("Failed to stat %s: %v", entry.Name(), err)
+				continue
+			}
+			if info.ModTime().After(cutoff) {
+				continue
+			}
+
+			path := filepath.Join(dir, entry.Name())
+			if dryRun {
+				log.Printf("[dry-run] would remove %s", path)
+			} else if err := os.Remove(path); err != nil {
+				log.Printf("Failed to remove %s: %v", path, err)
+				continue
+			}
+			result.Removed++
+			result.Bytes += info.Size()
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,95 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/radifan9/social-media-backend/internal/models"
+	"github.com/radifan9/social-media-backend/internal/repositories"
+	"github.com/radifan9/social-media-backend/pkg"
+)
+
+const postMediaDir = "public/post_images"
+
+// MediaWorker probes uploaded gifs and videos with ffmpeg and extracts a poster frame
+type MediaWorker struct {
+	mr       *repositories.MediaRepository
+	pr       *repositories.PostRepository
+	interval time.Duration
+	batch    int
+}
+
+func NewMediaWorker(mr *repositories.MediaRepository, pr *repositories.PostRepository) *MediaWorker {
+	return &MediaWorker{
+		mr:       mr,
+		pr:       pr,
+		interval: 5 * time.Second,
+		batch:    5,
+	}
+}
+
+// Start polls for pending media until ctx is cancelled
+func (m *MediaWorker) Start(ctx context.Context) {
+	log.Println("Media worker started")
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Media worker stopped")
+			return
+		case <-ticker.C:
+			m.runBatch(ctx)
+		}
+	}
+}
+
+func (m *MediaWorker) runBatch(ctx context.Context) {
+	jobs, err := m.mr.ClaimPendingMedia(ctx, m.batch)
+	if err != nil {
+		log.Printf("Failed to claim pending media: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if err := m.process(ctx, job); err != nil {
+			log.Printf("Media %s failed: %v", job.ID, err)
+			if err := m.mr.MarkMediaFailed(ctx, job.ID, err.Error()); err != nil {
+				log.Printf("Failed to mark media %s as failed: %v", job.ID, err)
+			}
+			continue
+		}
+
+		// The feed only shows ready media, so the cached post and the media-only timeline need a fresh copy
+		m.pr.InvalidatePostCache(ctx, job.PostID)
+		m.pr.InvalidateTimelineCache(ctx, job.UserID)
+	}
+}
+
+func (m *MediaWorker) process(ctx context.Context, job models.MediaJob) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	src := filepath.Join(postMediaDir, filepath.Base(job.MediaURL))
+
+	info, err := pkg.ProbeMedia(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	if limit := pkg.MaxMediaDuration(job.MediaType); limit > 0 && info.Duration > limit {
+		return fmt.Errorf("%s is %s long, limit is %s", job.MediaType, info.Duration.Round(time.Second), limit)
+	}
+
+	poster := strings.TrimSuffix(filepath.Base(job.MediaURL), filepath.Ext(job.MediaURL)) + "_poster.jpg"
+	if err := pkg.ExtractPosterFrame(ctx, src, filepath.Join(postMediaDir, poster)); err != nil {
+		return err
+	}
+
+	return m.mr.MarkMediaReady(ctx, job.ID, info, poster)
+}
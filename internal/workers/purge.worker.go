@@ -0,0 +1,59 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/radifan9/social-media-backend/internal/repositories"
+	"github.com/radifan9/social-media-backend/internal/utils"
+)
+
+// PurgeWorker hard deletes accounts whose deletion grace period is over
+type PurgeWorker struct {
+	ur       *repositories.UserRepository
+	interval time.Duration
+	batch    int
+}
+
+func NewPurgeWorker(ur *repositories.UserRepository) *PurgeWorker {
+	return &PurgeWorker{
+		ur:       ur,
+		interval: time.Hour,
+		batch:    50,
+	}
+}
+
+// Start purges due accounts every interval until ctx is cancelled
+func (p *PurgeWorker) Start(ctx context.Context) {
+	log.Println("Purge worker started")
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Purge worker stopped")
+			return
+		case <-ticker.C:
+			p.runBatch(ctx)
+		}
+	}
+}
+
+func (p *PurgeWorker) runBatch(ctx context.Context) {
+	for range p.batch {
+		userID, files, err := p.ur.PurgeDeletedAccount(ctx)
+		if err != nil {
+			log.Printf("Failed to purge deleted account: %v", err)
+			return
+		}
+		if userID == "" {
+			return
+		}
+
+		// Rows are gone, so the uploads are no longer referenced
+		utils.RemoveFiles(files...)
+		log.Printf("Purged account %s and %d files", userID, len(files))
+	}
+}
@@ -0,0 +1,63 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/radifan9/social-media-backend/internal/filters"
+	"github.com/radifan9/social-media-backend/internal/repositories"
+)
+
+// SchedulerWorker publishes scheduled posts once their publish time has come
+type SchedulerWorker struct {
+	pr       *repositories.PostRepository
+	filter   filters.ContentFilter
+	interval time.Duration
+	batch    int
+}
+
+func NewSchedulerWorker(pr *repositories.PostRepository, filter filters.ContentFilter) *SchedulerWorker {
+	return &SchedulerWorker{
+		pr:       pr,
+		filter:   filter,
+		interval: 30 * time.Second,
+		batch:    50,
+	}
+}
+
+// Start publishes due posts every interval until ctx is cancelled
+func (s *SchedulerWorker) Start(ctx context.Context) {
+	log.Println("Scheduler worker started")
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Scheduler worker stopped")
+			return
+		case <-ticker.C:
+			s.runBatch(ctx)
+		}
+	}
+}
+
+func (s *SchedulerWorker) runBatch(ctx context.Context) {
+	for range s.batch {
+		draftID, postID, err := s.pr.PublishDueDraft(ctx, s.filter)
+		if err != nil {
+			log.Printf("Failed to publish scheduled post: %v", err)
+			return
+		}
+		if draftID == "" {
+			return
+		}
+
+		if postID == "" {
+			log.Printf("Scheduled post %s was not published and went back to the drafts", draftID)
+			continue
+		}
+		log.Printf("Published scheduled post %s as post %s", draftID, postID)
+	}
+}
@@ -0,0 +1,59 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/radifan9/social-media-backend/internal/repositories"
+)
+
+// SuggestionWorker recomputes who-to-follow suggestions for every user.
+// Refreshing a user is idempotent, so replicas running it at the same time only repeat work
+type SuggestionWorker struct {
+	ur       *repositories.UserRepository
+	interval time.Duration
+	batch    int
+}
+
+func NewSuggestionWorker(ur *repositories.UserRepository) *SuggestionWorker {
+	return &SuggestionWorker{
+		ur:       ur,
+		interval: 6 * time.Hour,
+		batch:    100,
+	}
+}
+
+// Start refreshes all suggestions right away and then every interval until ctx is cancelled
+func (s *SuggestionWorker) Start(ctx context.Context) {
+	log.Println("Suggestion worker started")
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.refreshAll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Suggestion worker stopped")
+			return
+		case <-ticker.C:
+			s.refreshAll(ctx)
+		}
+	}
+}
+
+func (s *SuggestionWorker) refreshAll(ctx context.Context) {
+	lastID := ""
+	for ctx.Err() == nil {
+		next, err := s.ur.RefreshSuggestions(ctx, lastID, s.batch)
+		if err != nil {
+			log.Printf("Failed to refresh suggestions: %v", err)
+			return
+		}
+		if next == "" {
+			log.Println("Follow suggestions refreshed")
+			return
+		}
+		lastID = next
+	}
+}
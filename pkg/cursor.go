@@ -0,0 +1,119 @@
+package pkg
+
+import (
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Every id in a cursor is a row uuid
+var cursorIDRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// EncodeCursor packs the sort time and id of the last item of a page into an opaque string
+func EncodeCursor(t time.Time, id string) string {
+	raw := t.UTC().Format(time.RFC3339Nano) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor reverses EncodeCursor
+func DecodeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || !cursorIDRe.MatchString(parts[1]) {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+
+	return t, parts[1], nil
+}
+
+// ParseCursor decodes an optional cursor query value, an empty cursor means the first page
+func ParseCursor(cursor string) (*time.Time, *string, error) {
+	if cursor == "" {
+		return nil, nil, nil
+	}
+
+	t, id, err := DecodeCursor(cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &t, &id, nil
+}
+
+// EncodeRankCursor is the cursor for lists ordered by a relevance score
+func EncodeRankCursor(rank float32, id string) string {
+	return encodeScore(float64(rank), 32, id)
+}
+
+// ParseRankCursor decodes an optional rank cursor query value
+func ParseRankCursor(cursor string) (*float32, *string, error) {
+	if cursor == "" {
+		return nil, nil, nil
+	}
+
+	rank64, id, err := decodeScore(cursor, 32)
+	if err != nil {
+		return nil, nil, err
+	}
+	rank := float32(rank64)
+
+	return &rank, &id, nil
+}
+
+// EncodeScoreCursor is EncodeRankCursor for scores computed in Go, it keeps every bit of
+// the float64 so the next page starts exactly after the last item
+func EncodeScoreCursor(score float64, id string) string {
+	return encodeScore(score, 64, id)
+}
+
+// ParseScoreCursor decodes an optional score cursor query value
+func ParseScoreCursor(cursor string) (*float64, *string, error) {
+	if cursor == "" {
+		return nil, nil, nil
+	}
+
+	score, id, err := decodeScore(cursor, 64)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &score, &id, nil
+}
+
+// encodeScore writes the shortest text that reads back as the same float of bitSize bits
+func encodeScore(score float64, bitSize int, id string) string {
+	raw := strconv.FormatFloat(score, 'g', -1, bitSize) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeScore(cursor string, bitSize int) (float64, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || !cursorIDRe.MatchString(parts[1]) {
+		return 0, "", ErrInvalidCursor
+	}
+
+	score, err := strconv.ParseFloat(parts[0], bitSize)
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+
+	return score, parts[1], nil
+}
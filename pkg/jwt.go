@@ -67,20 +67,23 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// TokenLifetime is how long an issued token stays valid
+const TokenLifetime = 60 * time.Minute
+
 type Claims struct {
 	UserId string `json:"id"`
-	// Role   string `json:"role"`
+	Role   string `json:"role"`
 	jwt.RegisteredClaims
 }
 
-func NewJWTClaims(userid string) *Claims {
+func NewJWTClaims(userid string, role string) *Claims {
 	now := time.Now()
 	return &Claims{
 		UserId: userid,
-		// Role:   role,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute * 60)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(TokenLifetime)),
 			Issuer:    os.Getenv("JWT_ISSUER"),
 		},
 	}
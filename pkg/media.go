@@ -0,0 +1,140 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	MediaImage = "image"
+	MediaGIF   = "gif"
+	MediaVideo = "video"
+)
+
+var (
+	imageExtRe = regexp.MustCompile(`(?i)\.(png|jpg|jpeg|webp)$`)
+	gifExtRe   = regexp.MustCompile(`(?i)\.gif$`)
+	videoExtRe = regexp.MustCompile(`(?i)\.(mp4|webm)$`)
+)
+
+// Upload limits per media type
+var (
+	maxMediaSize = map[string]int64{
+		MediaImage: 5 << 20,
+		MediaGIF:   10 << 20,
+		MediaVideo: 50 << 20,
+	}
+	maxMediaDuration = map[string]time.Duration{
+		MediaGIF:   15 * time.Second,
+		MediaVideo: 60 * time.Second,
+	}
+)
+
+// DetectMediaType returns the media type for a file extension
+func DetectMediaType(ext string) (string, bool) {
+	switch {
+	case imageExtRe.MatchString(ext):
+		return MediaImage, true
+	case gifExtRe.MatchString(ext):
+		return MediaGIF, true
+	case videoExtRe.MatchString(ext):
+		return MediaVideo, true
+	}
+	return "", false
+}
+
+// MaxMediaSize returns the upload size limit in bytes for a media type
+func MaxMediaSize(mediaType string) int64 {
+	return maxMediaSize[mediaType]
+}
+
+// MaxMediaDuration returns the playback limit for a media type, zero means no limit
+func MaxMediaDuration(mediaType string) time.Duration {
+	return maxMediaDuration[mediaType]
+}
+
+type MediaInfo struct {
+	Duration time.Duration
+	Width    int
+	Height   int
+}
+
+func ffprobePath() string {
+	if p := os.Getenv("FFPROBE_PATH"); p != "" {
+		return p
+	}
+	return "ffprobe"
+}
+
+func ffmpegPath() string {
+	if p := os.Getenv("FFMPEG_PATH"); p != "" {
+		return p
+	}
+	return "ffmpeg"
+}
+
+// ProbeMedia reads duration and dimensions of a media file with ffprobe
+func ProbeMedia(ctx context.Context, path string) (MediaInfo, error) {
+	out, err := exec.CommandContext(ctx, ffprobePath(),
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=width,height:format=duration",
+		"-of", "json",
+		path,
+	).Output()
+	if err != nil {
+		return MediaInfo{}, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	var probe struct {
+		Streams []struct {
+			Width  int `json:"width"`
+			Height int `json:"height"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return MediaInfo{}, fmt.Errorf("invalid ffprobe output: %w", err)
+	}
+	if len(probe.Streams) == 0 {
+		return MediaInfo{}, errors.New("no video stream found")
+	}
+
+	var info MediaInfo
+	info.Width = probe.Streams[0].Width
+	info.Height = probe.Streams[0].Height
+	if probe.Format.Duration != "" {
+		seconds, err := strconv.ParseFloat(probe.Format.Duration, 64)
+		if err != nil {
+			return MediaInfo{}, fmt.Errorf("invalid duration: %w", err)
+		}
+		info.Duration = time.Duration(seconds * float64(time.Second))
+	}
+
+	return info, nil
+}
+
+// ExtractPosterFrame writes the first frame of src as a jpeg image to dst
+func ExtractPosterFrame(ctx context.Context, src, dst string) error {
+	out, err := exec.CommandContext(ctx, ffmpegPath(),
+		"-y",
+		"-v", "error",
+		"-i", src,
+		"-frames:v", "1",
+		"-q:v", "3",
+		dst,
+	).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg failed: %w: %s", err, out)
+	}
+	return nil
+}